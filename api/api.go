@@ -1,50 +1,272 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
 	gosifter "github.com/jtuki/gosifter/src"
 	"reflect"
 )
 
+// 由 gosifter-gen（cmd/gosifter-gen）生成的筛选方法；传入的值实现了此接口时 SiftStruct() 直接调用，不再经过反射。
+type LevelSifter interface {
+	SiftLevel(clevel int) (map[string]interface{}, error)
+}
+
+// 由 gosifter-gen（cmd/gosifter-gen）生成的序列化方法；传入的值实现了此接口时 Marshal() 直接调用，不再经过反射。
+type LevelMarshaler interface {
+	MarshalJSONLevel(clevel int) ([]byte, error)
+}
+
 // api function
 //
 // @param
-//  s - 需要执行筛选/脱敏的结构体对象（或者其指针）
+//  s - 需要执行筛选/脱敏的结构体对象（或者其指针）；nil（包括 nil 指针）返回 nil。键为字符串的映射等筛选结果为
+//  map[string]interface{} 的值同样可以传入，其他类型（如切片）的筛选结果不是 map，会返回错误，请使用 Sift() 或者 Marshal()
 //  clevel - 最高允许的安全等级（高于此等级的将被筛除）
 func SiftStruct(s interface{}, clevel int) (map[string]interface{}, error) {
-	isPtr := false // s是否是指针类型
-	rt := reflect.TypeOf(s)
-	if rt.Kind() == reflect.Ptr {
-		rt = rt.Elem()
-		isPtr = true
+	if isNilPtr(s) {
+		return nil, nil
 	}
-
-	if rt.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("invalid param type %v", rt.Kind())
+	rt, err := structType(s)
+	if err != nil {
+		v, err := gosifter.Sift(s, clevel)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			return m, nil
+		}
+		return nil, fmt.Errorf("invalid param type %v: the sifted result is not a map, use Sift() or Marshal() instead", reflect.TypeOf(s).Kind())
+	}
+	if ls, ok := s.(LevelSifter); ok {
+		return ls.SiftLevel(clevel)
 	}
 
 	if cs, err := gosifter.GetSifter(rt); err != nil {
 		return nil, err
 	} else {
-		if !isPtr {
-			return cs.SiftStruct(s, clevel)
-		} else { // pointer type to struct
-			return cs.SiftStruct(reflect.ValueOf(s).Elem().Interface(), clevel)
-		}
+		// 指针类型直接传入（保持域值可寻址）
+		return cs.SiftStruct(s, clevel)
 	}
 }
 
 // api function
 //
-// 封装的序列化操作，返回序列化之后的结果和可能的错误。
-func Marshal(s interface{}, clevel int) ([]byte, error) {
-	if clevel == CONFIDENTIAL_LEVEL_MAX {
-		return json.Marshal(s)
+// 对任意的值（结构体、切片、映射、指针等）进行筛选：其中的结构体（包括嵌套的）被转换为 map[string]interface{}，
+// 切片/数组转换为 []interface{}，其余的值原样返回；nil（包括 nil 指针）返回 nil。
+//
+// @param
+//  s - 任意可以进行 json 序列化的值
+//  clevel - 最高允许的安全等级（高于此等级的将被筛除）
+func Sift(s interface{}, clevel int) (interface{}, error) {
+	if isNilPtr(s) {
+		return nil, nil
+	}
+	if ls, ok := s.(LevelSifter); ok {
+		return ls.SiftLevel(clevel)
 	}
-	m, err := SiftStruct(s, clevel)
+	return gosifter.Sift(s, clevel)
+}
+
+// api function
+//
+// 与 SiftStruct() 相同，但是深拷贝结果中的切片、映射、指针等引用类型的值：修改结果不会影响原对象（反之亦然），
+// 结果可以安全地缓存或者交给其他 goroutine 使用。
+//
+// Note:
+//  1. 不会调用生成的 SiftLevel() 方法（生成的方法直接输出原值）；
+//  2. 结构体中非公开的域同样被深拷贝（*time.Location、reflect.Type 以及 reflect.Value 除外，依然与原对象共享）。
+func SiftStructCopy(s interface{}, clevel int) (map[string]interface{}, error) {
+	if isNilPtr(s) {
+		return nil, nil
+	}
+	rt, err := structType(s)
 	if err != nil {
 		return nil, err
 	}
-	return json.Marshal(m)
+	cs, err := gosifter.GetSifter(rt)
+	if err != nil {
+		return nil, err
+	}
+	return cs.SiftStructCopy(s, clevel)
+}
+
+// api function
+//
+// 返回 v 的深拷贝（类型与 v 一致），其中保密级别高于 clevel 的结构体域被清零；嵌套的结构体、指针、切片、映射以及
+// 接口的动态值同样递归地处理。用于需要保持原有类型的场景（如模板、gob、protobuf 的转换等）。
+//
+// Note:
+//  1. 与 SiftStruct() 不同，v 可以是任意类型（如结构体的切片）；
+//  2. 非公开的域与公开的域一样深拷贝以及筛选，映射的键同样被筛选（筛选之后不同的键变为相同时返回错误）；
+//  3. *time.Location、reflect.Type 以及 reflect.Value 不做拷贝，依然与原对象共享。
+func SiftCopy[T any](v T, clevel int) (T, error) {
+	out, err := gosifter.SiftValue(reflect.ValueOf(&v).Elem(), clevel)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	t, _ := out.Interface().(T) // T 为接口类型且 v 为 nil 时得到 nil
+	return t, nil
+}
+
+// api function
+//
+// 与 SiftStruct() 相同，但结果保持结构体域的声明顺序（嵌套的结构体同样如此），序列化之后与 Marshal() 的结果一致。
+//
+// @param
+//  s - 需要执行筛选/脱敏的结构体对象（或者其指针）
+//  clevel - 最高允许的安全等级（高于此等级的将被筛除）
+func SiftOrdered(s interface{}, clevel int) (OrderedMap, error) {
+	if isNilPtr(s) {
+		return nil, nil
+	}
+	rt, err := structType(s)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := gosifter.GetSifter(rt)
+	if err != nil {
+		return nil, err
+	}
+	return cs.SiftOrdered(s, clevel)
+}
+
+// 保持结构体域声明顺序的筛选结果（参考 SiftOrdered()）
+type OrderedMap = gosifter.OrderedMap
+
+type OrderedItem = gosifter.OrderedItem
+
+// api function
+//
+// 封装的序列化操作，返回序列化之后的结果和可能的错误。
+//
+// Note:
+//  1. 直接按照保密级别输出 json（不经过中间的 map），键的顺序与结构体域的声明顺序一致（与 json.Marshal() 以及
+//  SiftOrdered() 的结果一致），各个保密级别下的输出仅缺少被筛除的域；
+//  2. s 可以是任意可以进行 json 序列化的值（如结构体的切片、映射），其中的结构体同样被筛选；nil（包括 nil 指针）
+//  输出为 null。
+func Marshal(s interface{}, clevel int) ([]byte, error) {
+	if lm, ok := s.(LevelMarshaler); ok && !isNilPtr(s) {
+		return lm.MarshalJSONLevel(clevel)
+	}
+	return gosifter.MarshalLevel(s, clevel)
+}
+
+// api function
+//
+// 按照保密级别输出 MessagePack（用于内部的 RPC），保密级别的筛选规则与 Marshal() 完全一致。
+//
+// Note:
+//  1. 域的选择以及键名优先使用 msgpack 标签（`msgpack:"name,omitempty"`，`msgpack:"-"` 表示忽略，`json:"-"` 但设置了
+//  msgpack 标签的域依然输出），没有 msgpack 标签时使用 json 标签中的别名以及 omitempty/omitzero 选项；设置了别名的
+//  嵌入结构体嵌套输出；同名键按照与 json 相同的规则处理；
+//  2. time.Time 输出为 timestamp 扩展类型，[]byte 输出为 bin，实现了 MsgpackMarshaler 的类型直接输出其编码，
+//  仅实现了 json.Marshaler 的类型将其 json 转换为 MessagePack 输出；
+//  3. s 可以是任意值，nil（包括 nil 指针）输出为 nil。
+func MarshalMsgpack(s interface{}, clevel int) ([]byte, error) {
+	return gosifter.MarshalMsgpackLevel(s, clevel)
+}
+
+// api function
+//
+// 按照保密级别输出 YAML（用于导出配置等），筛选规则与 Marshal() 完全一致，键的顺序与结构体域的声明顺序一致。
+//
+// Note:
+//  1. yaml 标签：`yaml:"name,omitempty"` 设置键名以及 omitempty 选项（空值的判断与 yaml 一致；没有 yaml 标签时使用
+//  json 标签中的别名以及 omitempty/omitzero 选项），`yaml:"-"` 表示忽略，`yaml:",inline"` 将结构体或者映射的域展开
+//  至当前层（展开的键重复时返回错误），`yaml:",flow"` 按照 flow 风格输出；重命名之后的同名键按照与 json 相同的规则处理；
+//  2. 匿名结构体域与 json 一样总是被展开，`json:"-"` 的域同样被忽略；
+//  3. s 可以是任意值，nil（包括 nil 指针）输出为 null。
+func MarshalYAML(s interface{}, clevel int) ([]byte, error) {
+	return gosifter.MarshalYAMLLevel(s, clevel)
+}
+
+// api function
+//
+// 按照保密级别输出 XML（用于对接只支持 XML 的合作方），筛选规则与 Marshal() 完全一致，高于 clevel 的子元素以及属性均被筛除。
+//
+// Note:
+//  1. 域只按照 xml 标签选择以及命名（`json:"-"` 不影响 XML 输出），规则与 encoding/xml 一致：`xml:"name,attr"`
+//  输出为属性，`xml:",chardata"`、`xml:",innerxml"` 分别输出为文本以及原始 XML，`xml:"a>b"` 将元素嵌套在 <a> 中，
+//  `xml:"ns name"` 设置命名空间，`xml:",omitempty"` 忽略空值，`xml:"-"` 表示忽略；
+//  2. 元素名依次取 XMLName 域的标签、XMLName 域的值、域的元素名、结构体的类型名；与 encoding/xml 不同的是，
+//  带命名空间的属性返回错误；
+//  3. s 可以是任意值，nil（包括 nil 指针）输出为空，映射等 XML 无法表示的类型返回错误。
+func MarshalXML(s interface{}, clevel int) ([]byte, error) {
+	return gosifter.MarshalXMLLevel(s, clevel)
+}
+
+// 自定义 MessagePack 序列化方式的类型（MarshalMsgpack() 返回完整的 MessagePack 编码）
+type MsgpackMarshaler = gosifter.MsgpackMarshaler
+
+// api function
+//
+// 一次遍历输出所有保密级别下的序列化结果（用于将同一个对象分发给不同级别的订阅者），结果的下标即保密级别
+// （CONFIDENTIAL_LEVEL0 ~ CONFIDENTIAL_LEVEL_MAX），每个级别的结果与 Marshal() 一致。
+//
+// Note:
+//  与保密级别无关的部分（如不包含受限域的嵌套结构体）只序列化一次，多个级别共享其序列化结果。
+func MarshalAllLevels(s interface{}) ([][]byte, error) {
+	return gosifter.MarshalAllLevels(s)
+}
+
+// api function
+//
+// 注册结构体类型（一般在 init() 中调用）：预先校验标签并构建 sifter 以及各个保密级别下的 encoder，
+// 使得标签错误以及无法序列化的域类型（chan、func、键类型不合法的映射等）在启动时即可发现，
+// 而不是在处理第一个请求时才发现。
+//
+// @param
+//  types - 结构体对象（或者其指针），如 `Register(DeviceInfo{}, (*User)(nil))`
+func Register(types ...interface{}) error {
+	for _, t := range types {
+		rt, err := structType(t)
+		if err != nil {
+			return err
+		}
+		if err := gosifter.Register(rt); err != nil {
+			return fmt.Errorf("invalid type %v: %s", rt, err)
+		}
+	}
+	return nil
+}
+
+// api function
+//
+// 设置是否拒绝尚未注册的类型（包括接口的动态类型）；开启之后 SiftStruct/Marshal 遇到尚未注册的类型时
+// 返回 *UnregisteredTypeError。从已经注册的类型静态引用的结构体类型（嵌套的域、指针、切片等）视为已经注册。
+//
+// Note:
+//  开启之前已经使用过的类型（sifter 已经被缓存）同样视为已经注册，因此应当在 init() 中注册完成之后、
+//  处理请求之前开启。
+func RejectUnregistered(reject bool) {
+	gosifter.SetRejectUnregistered(reject)
+}
+
+// 开启 RejectUnregistered() 之后使用尚未注册的类型时返回的错误
+type UnregisteredTypeError = gosifter.UnregisteredTypeError
+
+// 是否为 nil 或者 nil 指针
+func isNilPtr(s interface{}) bool {
+	if s == nil {
+		return true
+	}
+	rv := reflect.ValueOf(s)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// 获取结构体对象（或者其指针）的结构体类型
+func structType(s interface{}) (reflect.Type, error) {
+	rt := reflect.TypeOf(s)
+	if rt == nil {
+		return nil, fmt.Errorf("invalid param type %v", rt)
+	}
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid param type %v", rt.Kind())
+	}
+	return rt, nil
 }
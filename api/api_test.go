@@ -1,17 +1,28 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	gosifter "github.com/jtuki/gosifter/src"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // 先序列化再反序列化
 //
 // @param
-//  name - name separator 用于输出
-//  s - 原始数据存在的对象
-//  us1 us2 - 用于 unmarshal 操作的对象
+//
+//	name - name separator 用于输出
+//	s - 原始数据存在的对象
+//	us1 us2 - 用于 unmarshal 操作的对象
 func marshalThenUnmarshal(t *testing.T, name string, s interface{}, us1, us2 interface{}) {
 	fmt.Printf("=== %s ===\n", name)
 
@@ -70,6 +81,28 @@ func leveldMarshal(t *testing.T, name string, s interface{}, maxConfidentialLeve
 			fmt.Printf("siftedS1 json: %s\n", siftBytes)
 		}
 	}
+
+	// 直接序列化的结果与 SiftStruct() 的结果一致
+	if marshalBytes, err := Marshal(s, maxConfidentialLevel); err != nil {
+		t.Fatal(err)
+	} else {
+		fmt.Printf("marshal json: %s\n", marshalBytes)
+		assertSameJsonContent(t, name, siftBytes, marshalBytes)
+	}
+}
+
+// 比较两个 json 序列化结果的内容是否一致（忽略键的顺序）
+func assertSameJsonContent(t *testing.T, name string, b1, b2 []byte) {
+	var v1, v2 interface{}
+	if err := json.Unmarshal(b1, &v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b2, &v2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v1, v2) {
+		t.Fatalf("%s: not the same: %s vs %s", name, b1, b2)
+	}
 }
 
 func TestSiftStruct(t *testing.T) {
@@ -184,3 +217,2366 @@ func TestSiftStruct(t *testing.T) {
 		leveldMarshal(t, "s3-level3", &s3, CONFIDENTIAL_LEVEL3)
 	}()
 }
+
+func TestSiftPointerFields(t *testing.T) {
+	type Meta struct {
+		Country string `json:"country"`
+		IP      string `json:"ip" confidential:"level3"`
+	}
+	type P1 struct {
+		Meta     *Meta  `json:"meta"`
+		MetaPP   **Meta `json:"meta_pp"`
+		NilMeta  *Meta  `json:"nil_meta"`
+		OmitMeta *Meta  `json:"omit_meta,omitempty"`
+		Name     *string
+	}
+
+	name := "p1"
+	meta := &Meta{Country: "cn", IP: "1.2.3.4"}
+	p1 := P1{Meta: meta, MetaPP: &meta, Name: &name}
+
+	leveldMarshal(t, "p1-level0", &p1, CONFIDENTIAL_LEVEL0)
+
+	m, err := SiftStruct(&p1, CONFIDENTIAL_LEVEL0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"meta", "meta_pp"} {
+		mm, ok := m[k].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s: expect sifted map, got %T", k, m[k])
+		}
+		if _, exist := mm["ip"]; exist || mm["country"] != "cn" {
+			t.Fatalf("%s: unexpected sifted result %v", k, mm)
+		}
+	}
+	if v, exist := m["nil_meta"]; !exist || v != nil {
+		t.Fatalf("nil_meta: expect null, got %v", v)
+	}
+	if _, exist := m["omit_meta"]; exist {
+		t.Fatalf("omit_meta: should be omitted")
+	}
+
+	// 最高级别下与 json 标准库的序列化结果一致
+	func() {
+		var us1, us2 P1
+		marshalThenUnmarshal(t, "p1", &p1, &us1, &us2)
+		if (*us1.Meta) != (*us2.Meta) || (**us1.MetaPP) != (**us2.MetaPP) || us2.NilMeta != nil || *us2.Name != name {
+			t.Fatalf("unmarshal: not the same")
+		}
+	}()
+}
+
+func TestSiftSliceFields(t *testing.T) {
+	type Device struct {
+		Id string `json:"id"`
+		IP string `json:"ip" confidential:"level2"`
+	}
+	type L1 struct {
+		Devices    []Device    `json:"devices"`
+		DevicePtrs []*Device   `json:"device_ptrs"`
+		DeviceArr  [2]Device   `json:"device_arr"`
+		Nested     [][]*Device `json:"nested"`
+		NilDevices []Device    `json:"nil_devices"`
+		Tags       []string    `json:"tags"`
+	}
+
+	l1 := L1{
+		Devices:    []Device{{Id: "d0", IP: "1.1.1.1"}, {Id: "d1", IP: "2.2.2.2"}},
+		DevicePtrs: []*Device{{Id: "d2", IP: "3.3.3.3"}, nil},
+		DeviceArr:  [2]Device{{Id: "d3", IP: "4.4.4.4"}},
+		Nested:     [][]*Device{{{Id: "d4", IP: "5.5.5.5"}}},
+		Tags:       []string{"a", "b"},
+	}
+
+	leveldMarshal(t, "l1-level1", l1, CONFIDENTIAL_LEVEL1)
+
+	m, err := SiftStruct(l1, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"device_arr":[{"id":"d3"},{"id":""}],"device_ptrs":[{"id":"d2"},null],` +
+		`"devices":[{"id":"d0"},{"id":"d1"}],"nested":[[{"id":"d4"}]],"nil_devices":null,"tags":["a","b"]}`
+	if string(siftBytes) != expect {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+
+	func() {
+		var us1, us2 L1
+		marshalThenUnmarshal(t, "l1", l1, &us1, &us2)
+		if !reflect.DeepEqual(us1, us2) {
+			t.Fatalf("unmarshal: not the same")
+		}
+	}()
+}
+
+// 实现了 encoding.TextMarshaler 的映射键
+type textKey struct {
+	a, b string
+}
+
+func (k textKey) MarshalText() ([]byte, error) {
+	return []byte(k.a + "-" + k.b), nil
+}
+
+func (k *textKey) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), "-", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid text key %s", text)
+	}
+	k.a, k.b = parts[0], parts[1]
+	return nil
+}
+
+func TestSiftMapFields(t *testing.T) {
+	type Meta struct {
+		Country string `json:"country"`
+		IP      string `json:"ip" confidential:"level1"`
+	}
+	type M1 struct {
+		ByName  map[string]Meta    `json:"by_name"`
+		ById    map[int64]*Meta    `json:"by_id"`
+		ByUint  map[uint8][]Meta   `json:"by_uint"`
+		ByText  map[textKey]Meta   `json:"by_text"`
+		NilMap  map[string]Meta    `json:"nil_map"`
+		Counter map[string]float64 `json:"counter"`
+	}
+
+	meta := Meta{Country: "cn", IP: "1.2.3.4"}
+	m1 := M1{
+		ByName:  map[string]Meta{"a": meta},
+		ById:    map[int64]*Meta{-1: &meta, 2: nil},
+		ByUint:  map[uint8][]Meta{7: {meta}},
+		ByText:  map[textKey]Meta{{"x", "y"}: meta},
+		Counter: map[string]float64{"c": 1.5},
+	}
+
+	leveldMarshal(t, "m1-level0", m1, CONFIDENTIAL_LEVEL0)
+
+	m, err := SiftStruct(m1, CONFIDENTIAL_LEVEL0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"by_id":{"-1":{"country":"cn"},"2":null},"by_name":{"a":{"country":"cn"}},` +
+		`"by_text":{"x-y":{"country":"cn"}},"by_uint":{"7":[{"country":"cn"}]},"counter":{"c":1.5},"nil_map":null}`
+	if string(siftBytes) != expect {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+
+	func() {
+		var us1, us2 M1
+		marshalThenUnmarshal(t, "m1", m1, &us1, &us2)
+		if !reflect.DeepEqual(us1, us2) {
+			t.Fatalf("unmarshal: not the same")
+		}
+	}()
+}
+
+func TestSiftInterfaceFields(t *testing.T) {
+	type User struct {
+		Name     string `json:"name"`
+		Password string `json:"password" confidential:"level3"`
+	}
+	type Envelope struct {
+		Code    int           `json:"code"`
+		Data    interface{}   `json:"data"`
+		Payload any           `json:"payload"`
+		List    []interface{} `json:"list"`
+		Empty   interface{}   `json:"empty"`
+	}
+
+	user := User{Name: "u", Password: "secret"}
+	env := Envelope{
+		Code:    0,
+		Data:    user,
+		Payload: &Envelope{Code: 1, Data: []*User{&user}},
+		List:    []interface{}{"plain", 1, map[string]User{"u": user}},
+	}
+
+	leveldMarshal(t, "envelope-level2", env, CONFIDENTIAL_LEVEL2)
+
+	m, err := SiftStruct(env, CONFIDENTIAL_LEVEL2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(siftBytes), "secret") {
+		t.Fatalf("level3 data leaked: %s", siftBytes)
+	}
+	expect := `{"code":0,"data":{"name":"u"},"empty":null,"list":["plain",1,{"u":{"name":"u"}}],` +
+		`"payload":{"code":1,"data":[{"name":"u"}],"empty":null,"list":null,"payload":null}}`
+	if string(siftBytes) != expect {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+}
+
+func TestSiftRecursiveTypes(t *testing.T) {
+	type Node struct {
+		Name     string  `json:"name"`
+		Salary   int     `json:"salary" confidential:"level2"`
+		Children []Node  `json:"children,omitempty"`
+		Parent   *Node   `json:"-"`
+		Manager  *Node   `json:"manager,omitempty"`
+		Peers    []*Node `json:"peers,omitempty"`
+	}
+
+	ceo := &Node{Name: "ceo", Salary: 100}
+	cto := Node{Name: "cto", Salary: 80, Parent: ceo, Manager: &Node{Name: "ceo-copy", Salary: 100}}
+	cto.Children = []Node{{Name: "dev", Salary: 50}}
+	ceo.Children = []Node{cto}
+
+	leveldMarshal(t, "node-level1", ceo, CONFIDENTIAL_LEVEL1)
+
+	m, err := SiftStruct(ceo, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"children":[{"children":[{"name":"dev"}],"manager":{"name":"ceo-copy"},"name":"cto"}],"name":"ceo"}`
+	if string(siftBytes) != expect {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+
+	// 循环引用（运行时检测）
+	loop := &Node{Name: "loop"}
+	loop.Peers = []*Node{loop}
+	if _, err := SiftStruct(loop, CONFIDENTIAL_LEVEL1); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expect cycle error, got %v", err)
+	} else {
+		fmt.Println("cycle error:", err)
+	}
+
+	// 互相引用的类型
+	type B struct {
+		Secret string      `json:"secret" confidential:"level3"`
+		Next   interface{} `json:"next"`
+	}
+	type A struct {
+		Bs []B `json:"bs"`
+	}
+	a := A{Bs: []B{{Secret: "s", Next: &A{Bs: []B{{Secret: "s2"}}}}}}
+	m, err = SiftStruct(a, CONFIDENTIAL_LEVEL0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if siftBytes, err = json.Marshal(m); err != nil {
+		t.Fatal(err)
+	} else if string(siftBytes) != `{"bs":[{"next":{"bs":[{"next":null}]}}]}` {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+}
+
+// 自定义 json 序列化方式（值接收者）
+type money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m money) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"%d.%02d %s"`, m.Cents/100, m.Cents%100, m.Currency)), nil
+}
+
+// 自定义文本序列化方式（指针接收者）
+type maskedCard struct {
+	Number string `json:"number" confidential:"level3"`
+}
+
+func (c *maskedCard) MarshalText() ([]byte, error) {
+	return []byte("****" + c.Number[len(c.Number)-4:]), nil
+}
+
+func TestSiftMarshalerFields(t *testing.T) {
+	type Order struct {
+		Id        string               `json:"id"`
+		CreatedAt time.Time            `json:"created_at"`
+		Price     money                `json:"price"`
+		Cost      *money               `json:"cost" confidential:"level2"`
+		Card      maskedCard           `json:"card"`
+		History   map[string]time.Time `json:"history"`
+	}
+
+	order := Order{
+		Id:        "o1",
+		CreatedAt: time.Date(2017, 2, 6, 14, 24, 31, 0, time.UTC),
+		Price:     money{Cents: 12345, Currency: "CNY"},
+		Cost:      &money{Cents: 100, Currency: "CNY"},
+		Card:      maskedCard{Number: "6222020200001234"},
+		History:   map[string]time.Time{"paid": time.Date(2017, 2, 7, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// 最高级别下与 json 标准库的序列化结果一致（指针接收者的方法仅在可寻址时生效）
+	for _, s := range []interface{}{order, &order} {
+		m, err := SiftStruct(s, CONFIDENTIAL_LEVEL_MAX)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v1, v2 interface{}
+		jsonBytes, _ := json.Marshal(s)
+		siftBytes, _ := json.Marshal(m)
+		if err = json.Unmarshal(jsonBytes, &v1); err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal(siftBytes, &v2); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v1, v2) {
+			t.Fatalf("not the same: %s vs %s", jsonBytes, siftBytes)
+		}
+	}
+
+	m, err := SiftStruct(&order, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"card":"****1234","created_at":"2017-02-06T14:24:31Z","history":{"paid":"2017-02-07T00:00:00Z"},` +
+		`"id":"o1","price":"123.45 CNY"}`
+	if string(siftBytes) != expect {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+}
+
+// 比较最高级别下的筛选结果与 json 标准库的序列化结果是否一致（忽略键的顺序）
+func assertSameAsJson(t *testing.T, name string, s interface{}) {
+	jsonBytes, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := SiftStruct(s, CONFIDENTIAL_LEVEL_MAX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameJsonContent(t, name, jsonBytes, siftBytes)
+
+	// 直接序列化的结果（包括键的顺序）与 json 标准库完全一致
+	marshalBytes, err := Marshal(s, CONFIDENTIAL_LEVEL_MAX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(jsonBytes, marshalBytes) {
+		t.Fatalf("%s: marshal not the same as json: %s vs %s", name, jsonBytes, marshalBytes)
+	}
+}
+
+// 实现了 IsZero() 的类型（指针接收者）
+type zeroRange struct {
+	Lo, Hi int
+}
+
+func (r *zeroRange) IsZero() bool {
+	return r.Hi <= r.Lo
+}
+
+func TestSiftJsonTagOptions(t *testing.T) {
+	type J1 struct {
+		Id       int64     `json:"id,string"`
+		Name     string    `json:"name,omitempty,string"`
+		Ratio    float64   `json:",string"`
+		Ok       *bool     `json:"ok,string"`
+		NilOk    *bool     `json:"nil_ok,string"`
+		Ignored  []int     `json:"ignored,string"`
+		Dash     string    `json:"-,"`
+		Unknown  int       `json:"unknown,whatever,omitempty"`
+		At       time.Time `json:"at,omitzero"`
+		Range    zeroRange `json:"range,omitzero"`
+		Zero     [2]int    `json:"zero,omitzero"`
+		NonZero  [2]int    `json:"non_zero,omitzero"`
+		Both     []int     `json:"both,omitzero,omitempty"`
+		Password string    `json:"password,string" confidential:"level2"`
+	}
+
+	ok := true
+	j1 := J1{
+		Id:       9007199254740993,
+		Name:     "<name>",
+		Ratio:    0.000001,
+		Ok:       &ok,
+		Ignored:  []int{1},
+		Dash:     "dash",
+		Range:    zeroRange{Lo: 2, Hi: 1},
+		NonZero:  [2]int{0, 1},
+		Both:     []int{},
+		Password: "p",
+	}
+
+	assertSameAsJson(t, "j1", j1)
+	assertSameAsJson(t, "j1-ptr", &j1)
+	assertSameAsJson(t, "j1-zero", J1{})
+
+	m, err := SiftStruct(j1, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"-":"dash","Ratio":"0.000001","id":"9007199254740993","ignored":[1],` +
+		`"name":"\"\\u003cname\\u003e\"","nil_ok":null,"non_zero":[0,1],"ok":"true"}`
+	if string(siftBytes) != expect {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+
+	// 非法的别名按照没有设置别名处理（encoding/json v1 的规则）
+	type J2 struct {
+		Invalid string `json:"in\"valid,omitempty"`
+	}
+	if m, err = SiftStruct(J2{Invalid: "v"}, CONFIDENTIAL_LEVEL0); err != nil {
+		t.Fatal(err)
+	} else if len(m) != 1 || m["Invalid"] != "v" {
+		t.Fatalf("unexpected sifted result %v", m)
+	}
+}
+
+func TestSiftEmbeddedConflicts(t *testing.T) {
+	type Base struct {
+		Id   int `json:"id"`
+		Code string
+		Note string
+	}
+	type Audit struct {
+		Code    string
+		Note    string `json:"Note"`
+		Creator string `json:"creator" confidential:"level2"`
+	}
+	type Inner struct {
+		Deep string `json:"deep"`
+	}
+	type Outer struct {
+		Inner
+		Deep string `json:"deep"`
+	}
+	type E1 struct {
+		Base  // Code 与 Audit 冲突（同一层次且都没有设置标签，全部忽略）
+		Audit `confidential:"level1"`
+		Outer
+		Id int `json:"id"` // 较浅的层次优先
+	}
+
+	e1 := E1{
+		Base:  Base{Id: 1, Code: "base-code", Note: "base-note"},
+		Audit: Audit{Code: "audit-code", Note: "audit-note", Creator: "c"},
+		Outer: Outer{Inner: Inner{Deep: "inner"}, Deep: "outer"},
+		Id:    100,
+	}
+
+	assertSameAsJson(t, "e1", e1)
+
+	m, err := SiftStruct(e1, CONFIDENTIAL_LEVEL0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Note 的标签来自 Audit（设置了标签的优先），但 Audit 整体的保密级别是 level1
+	if string(siftBytes) != `{"deep":"outer","id":100}` {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+
+	// 同一类型被嵌入多次
+	type Twice struct {
+		Outer
+		Other struct {
+			Outer
+		}
+	}
+	assertSameAsJson(t, "twice", Twice{Outer: Outer{Deep: "d"}})
+}
+
+// 非公开的嵌入结构体（其中公开的域依然会被序列化）
+type auditInfo struct {
+	Creator string `json:"creator" confidential:"level2"`
+	Version int    `json:"version"`
+}
+
+func TestSiftEmbeddedPointers(t *testing.T) {
+	type Base struct {
+		Id     int64  `json:"id"`
+		Secret string `json:"secret" confidential:"level3"`
+	}
+	type Entity struct {
+		*Base
+		auditInfo
+		Name string `json:"name"`
+	}
+	type Tagged struct {
+		*Base `json:"base,omitempty"`
+		Name  string `json:"name"`
+	}
+	type Chain struct {
+		*Chain
+		Value string `json:"value"`
+	}
+
+	e1 := Entity{Base: &Base{Id: 1, Secret: "s"}, auditInfo: auditInfo{Creator: "c", Version: 2}, Name: "e1"}
+	assertSameAsJson(t, "entity", e1)
+	assertSameAsJson(t, "entity-nil-base", Entity{Name: "e2"})
+	assertSameAsJson(t, "tagged", Tagged{Base: &Base{Id: 3}})
+	assertSameAsJson(t, "tagged-nil-base", Tagged{Name: "t"})
+	assertSameAsJson(t, "chain", Chain{Chain: &Chain{Value: "inner"}, Value: "outer"})
+
+	m, err := SiftStruct(&e1, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siftBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(siftBytes) != `{"id":1,"name":"e1","version":2}` {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+
+	if m, err = SiftStruct(Entity{Name: "e2"}, CONFIDENTIAL_LEVEL1); err != nil {
+		t.Fatal(err)
+	} else if siftBytes, err = json.Marshal(m); err != nil {
+		t.Fatal(err)
+	} else if string(siftBytes) != `{"name":"e2","version":0}` {
+		t.Fatalf("unexpected sifted result %s", siftBytes)
+	}
+}
+
+// 输出带有空白的 json
+type spacedMarshaler struct{}
+
+func (spacedMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{ "html" : "<&>" , "list" : [ 1, 2 ] }`), nil
+}
+
+func TestMarshalEncoding(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Price int    `json:"price" confidential:"level1"`
+	}
+	type Enc struct {
+		Str     string                 `json:"str"`
+		Bytes   []byte                 `json:"bytes"`
+		NilByte []byte                 `json:"nil_bytes"`
+		Number  json.Number            `json:"number"`
+		Floats  []float64              `json:"floats"`
+		Float32 float32                `json:"float32"`
+		Uints   [3]uint8               `json:"uints"`
+		Spaced  spacedMarshaler        `json:"spaced"`
+		Any     interface{}            `json:"any"`
+		Items   map[string][]*Item     `json:"items"`
+		Generic map[string]interface{} `json:"generic"`
+	}
+
+	enc := Enc{
+		Str:     "<a href=\"x\">\u2028\t\x01&</a>",
+		Bytes:   []byte("hello"),
+		Number:  json.Number("1.5e3"),
+		Floats:  []float64{0, 1e-7, 123456789, 1e21, -0.5},
+		Float32: 3.14,
+		Uints:   [3]uint8{1, 2, 3},
+		Any:     &Item{Name: "any", Price: 1},
+		Items:   map[string][]*Item{"b": {{Name: "b", Price: 2}}, "a": {nil}},
+		Generic: map[string]interface{}{"item": Item{Name: "g", Price: 3}, "n": nil},
+	}
+
+	assertSameAsJson(t, "enc", enc)
+	leveldMarshal(t, "enc-level0", enc, CONFIDENTIAL_LEVEL0)
+
+	// 不支持的类型仅在其可见时才报错
+	type Unsupported struct {
+		Name string   `json:"name"`
+		Chan chan int `json:"chan" confidential:"level3"`
+	}
+	if _, err := Marshal(Unsupported{}, CONFIDENTIAL_LEVEL3); err == nil {
+		t.Fatalf("expect unsupported type error")
+	}
+	if b, err := Marshal(Unsupported{}, CONFIDENTIAL_LEVEL2); err != nil || string(b) != `{"name":""}` {
+		t.Fatalf("unexpected marshal result %s, %v", b, err)
+	}
+
+	// 非法的浮点数
+	enc.Floats = []float64{math.Inf(1)}
+	if _, err := Marshal(enc, CONFIDENTIAL_LEVEL0); err == nil {
+		t.Fatalf("expect unsupported value error")
+	}
+
+	// 循环引用
+	type Loop struct {
+		Next *Loop `json:"next"`
+	}
+	loop := &Loop{}
+	loop.Next = loop
+	if _, err := Marshal(loop, CONFIDENTIAL_LEVEL0); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expect cycle error, got %v", err)
+	}
+}
+
+func TestSiftLevelBounds(t *testing.T) {
+	type Bound struct {
+		Public string `json:"public"`
+		Secret string `json:"secret" confidential:"level3"`
+	}
+	b := Bound{Public: "p", Secret: "s"}
+
+	// 低于 level0 时没有可见的域，高于最高级别时按照最高级别处理
+	for _, c := range []struct {
+		level  int
+		expect string
+	}{
+		{-1, `{}`},
+		{CONFIDENTIAL_LEVEL0, `{"public":"p"}`},
+		{CONFIDENTIAL_LEVEL_MAX, `{"public":"p","secret":"s"}`},
+		{CONFIDENTIAL_LEVEL_MAX + 1, `{"public":"p","secret":"s"}`},
+	} {
+		m, err := SiftStruct(b, c.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		siftBytes, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		marshalBytes, err := Marshal(b, c.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(siftBytes) != c.expect || string(marshalBytes) != c.expect {
+			t.Fatalf("level %d: unexpected result %s, %s", c.level, siftBytes, marshalBytes)
+		}
+	}
+}
+
+// 模拟 gosifter-gen 生成的方法（故意与反射路径的输出不同）
+type generatedStub struct {
+	Name string `json:"name"`
+}
+
+func (g *generatedStub) SiftLevel(clevel int) (map[string]interface{}, error) {
+	return map[string]interface{}{"generated": clevel}, nil
+}
+
+func (g *generatedStub) MarshalJSONLevel(clevel int) ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"generated":%d}`, clevel)), nil
+}
+
+// 模拟 gosifter-gen 为递归类型生成的 MarshalJSONLevel()（各个域共享同一个 AppendState）
+type generatedNode struct {
+	Name string         `json:"name"`
+	Next *generatedNode `json:"next"`
+}
+
+func (x *generatedNode) MarshalJSONLevel(clevel int) ([]byte, error) {
+	if x == nil {
+		return []byte("null"), nil
+	}
+	st := gosifter.NewAppendState()
+	defer st.Release()
+	if err := st.Enter(x); err != nil {
+		return nil, err
+	}
+	defer st.Leave(x)
+	var err error
+	b := append(make([]byte, 0, 256), `{"name":`...)
+	b = st.AppendString(b, x.Name)
+	b = append(b, `,"next":`...)
+	if b, err = st.AppendField(b, &x.Next, clevel); err != nil {
+		return nil, err
+	}
+	return append(b, '}'), nil
+}
+
+func TestGeneratedAppendState(t *testing.T) {
+	chain := func(n int) *generatedNode {
+		var head *generatedNode
+		for i := 0; i < n; i++ {
+			head = &generatedNode{Name: "<n>", Next: head}
+		}
+		return head
+	}
+
+	// 嵌套深度与反射路径一致（包括根节点本身）
+	for _, n := range []int{2, gosifter.MAX_SIFT_DEPTH, gosifter.MAX_SIFT_DEPTH + 1} {
+		x := chain(n)
+		b1, err1 := x.MarshalJSONLevel(CONFIDENTIAL_LEVEL1)
+		b2, err2 := gosifter.MarshalLevel(x, CONFIDENTIAL_LEVEL1)
+		if string(b1) != string(b2) || (err1 == nil) != (err2 == nil) {
+			t.Fatalf("%d nodes: MarshalJSONLevel() = %.40s, %v; MarshalLevel() = %.40s, %v", n, b1, err1, b2, err2)
+		}
+	}
+
+	// 循环引用
+	loop := chain(3)
+	loop.Next.Next.Next = loop
+	if _, err := loop.MarshalJSONLevel(CONFIDENTIAL_LEVEL1); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expect cycle error, got %v", err)
+	}
+
+	// 除了输出的结果之外不再分配内存
+	x := chain(2)
+	if n := testing.AllocsPerRun(100, func() { x.MarshalJSONLevel(CONFIDENTIAL_LEVEL1) }); n > 1 {
+		t.Fatalf("MarshalJSONLevel() allocates %v times per run", n)
+	}
+}
+
+func TestGeneratedMethods(t *testing.T) {
+	g := &generatedStub{Name: "stub"}
+
+	// 实现了生成的方法时优先调用
+	m, err := SiftStruct(g, CONFIDENTIAL_LEVEL1)
+	if err != nil || !reflect.DeepEqual(m, map[string]interface{}{"generated": CONFIDENTIAL_LEVEL1}) {
+		t.Fatalf("SiftStruct() = %v, %v", m, err)
+	}
+	b, err := Marshal(g, CONFIDENTIAL_LEVEL2)
+	if err != nil || string(b) != `{"generated":2}` {
+		t.Fatalf("Marshal() = %s, %v", b, err)
+	}
+
+	// 值类型没有实现（指针接收者的）生成的方法，依然经过反射路径
+	b, err = Marshal(*g, CONFIDENTIAL_LEVEL2)
+	if err != nil || string(b) != `{"name":"stub"}` {
+		t.Fatalf("Marshal() = %s, %v", b, err)
+	}
+}
+
+type registeredNested struct {
+	Secret string `json:"secret" confidential:"level2"`
+}
+
+type registeredType struct {
+	Name   string            `json:"name"`
+	Nested *registeredNested `json:"nested"`
+	Any    interface{}       `json:"any"`
+}
+
+type lateRegisteredType struct {
+	Name string `json:"name" confidential:"level1"`
+}
+
+func TestRegister(t *testing.T) {
+	type BadTag struct {
+		Name string `confidential:"level9"`
+	}
+	if err := Register(registeredType{}, &BadTag{}); err == nil {
+		t.Fatal("expect error for invalid confidential tag")
+	}
+	if err := Register(1); err == nil {
+		t.Fatal("expect error for non-struct type")
+	}
+	if err := Register((*registeredType)(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	RejectUnregistered(true)
+	defer RejectUnregistered(false)
+
+	// 已经注册的类型（以及静态引用的结构体类型）可以正常使用
+	r := &registeredType{Name: "r", Nested: &registeredNested{Secret: "s"}}
+	if b, err := Marshal(r, CONFIDENTIAL_LEVEL_MAX); err != nil || string(b) != `{"name":"r","nested":{"secret":"s"},"any":null}` {
+		t.Fatalf("Marshal() = %s, %v", b, err)
+	}
+
+	// 尚未注册的类型（包括接口的动态类型）
+	late := lateRegisteredType{Name: "late"}
+	r.Any = late
+	for _, v := range []interface{}{late, r} {
+		if _, err := SiftStruct(v, CONFIDENTIAL_LEVEL_MAX); err == nil {
+			t.Fatalf("SiftStruct(%T): expect unregistered type error", v)
+		}
+		_, err := Marshal(v, CONFIDENTIAL_LEVEL_MAX)
+		if _, ok := err.(*UnregisteredTypeError); !ok {
+			t.Fatalf("Marshal(%T): expect unregistered type error, got %v", v, err)
+		}
+	}
+
+	// 注册之后即可使用
+	if err := Register(late); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := Marshal(r, CONFIDENTIAL_LEVEL_MAX); err != nil || string(b) != `{"name":"r","nested":{"secret":"s"},"any":{"name":"late"}}` {
+		t.Fatalf("Marshal() = %s, %v", b, err)
+	}
+	if m, err := SiftStruct(late, CONFIDENTIAL_LEVEL0); err != nil || len(m) != 0 {
+		t.Fatalf("SiftStruct() = %v, %v", m, err)
+	}
+
+	// 无法序列化的域类型在注册时报错
+	type Key struct{ K int }
+	type WithChan struct {
+		Ch chan int `json:"ch"`
+	}
+	type WithFunc struct {
+		Nested []*struct {
+			Fn func() `json:"fn"`
+		} `json:"nested"`
+	}
+	type WithMap struct {
+		M map[Key]string `json:"m"`
+	}
+	type Skipped struct {
+		Ch   chan int `json:"-"`
+		Name string   `json:"name"`
+	}
+	for _, v := range []interface{}{WithChan{}, WithFunc{}, WithMap{}} {
+		if err := Register(v); err == nil {
+			t.Fatalf("Register(%T): expect error for unsupported field type", v)
+		}
+	}
+	if err := Register(Skipped{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type rejectToggledType struct {
+	Name string `json:"name"`
+}
+
+func TestRejectUnregisteredToggle(t *testing.T) {
+	RejectUnregistered(true)
+	v := rejectToggledType{Name: "a"}
+	if _, err := Marshal(v, CONFIDENTIAL_LEVEL1); err == nil {
+		RejectUnregistered(false)
+		t.Fatal("expect unregistered type error")
+	}
+
+	// 关闭之后 Marshal() 与 SiftStruct() 一样可以正常使用
+	RejectUnregistered(false)
+	if b, err := Marshal(v, CONFIDENTIAL_LEVEL1); err != nil || string(b) != `{"name":"a"}` {
+		t.Fatalf("Marshal() = %s, %v", b, err)
+	}
+	if m, err := SiftStruct(v, CONFIDENTIAL_LEVEL1); err != nil || m["name"] != "a" {
+		t.Fatalf("SiftStruct() = %v, %v", m, err)
+	}
+}
+
+type lateMultiType struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret" confidential:"level2"`
+}
+
+func TestRegisterAfterMarshalAllLevels(t *testing.T) {
+	RejectUnregistered(true)
+	defer RejectUnregistered(false)
+
+	v := lateMultiType{Name: "n", Secret: "s"}
+	if _, err := MarshalAllLevels(v); err == nil {
+		t.Fatal("expect unregistered type error")
+	}
+
+	// 注册之后此前缓存的报错 encoder 被清除
+	if err := Register(v); err != nil {
+		t.Fatal(err)
+	}
+	out, err := MarshalAllLevels(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out[CONFIDENTIAL_LEVEL1]) != `{"name":"n"}` || string(out[CONFIDENTIAL_LEVEL_MAX]) != `{"name":"n","secret":"s"}` {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	type Device struct {
+		Name   string          `json:"name"`
+		Html   string          `json:"html,omitempty"`
+		Spaced spacedMarshaler `json:"spaced"`
+		Secret string          `json:"secret" confidential:"level2"`
+	}
+	devices := []*Device{
+		{Name: "d1", Html: "<b>&</b>", Secret: "s1"},
+		{Name: "d2", Secret: "s2"},
+	}
+
+	// 最高保密级别下与 json.Encoder 的输出完全一致（包括多次 Encode() 复用缓冲区的情况）
+	for _, c := range []struct {
+		prefix, indent string
+		escapeHTML     bool
+	}{
+		{"", "", true},
+		{"", "", false},
+		{">", "\t", true},
+		{"", "  ", false},
+	} {
+		var expect, actual bytes.Buffer
+		jenc := json.NewEncoder(&expect)
+		jenc.SetIndent(c.prefix, c.indent)
+		jenc.SetEscapeHTML(c.escapeHTML)
+		enc := NewEncoder(&actual, CONFIDENTIAL_LEVEL_MAX)
+		enc.SetIndent(c.prefix, c.indent)
+		enc.SetEscapeHTML(c.escapeHTML)
+
+		for _, v := range []interface{}{devices, devices[0], nil, devices} {
+			if err := jenc.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if expect.String() != actual.String() {
+			t.Fatalf("%+v: encoder not the same as json:\n%s\nvs\n%s", c, expect.String(), actual.String())
+		}
+	}
+
+	// 按照保密级别筛选
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, CONFIDENTIAL_LEVEL1)
+	if err := enc.Encode(devices); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&generatedStub{}); err != nil {
+		t.Fatal(err)
+	}
+	expect := `[{"name":"d1","html":"\u003cb\u003e\u0026\u003c/b\u003e","spaced":{"html":"\u003c\u0026\u003e","list":[1,2]}},` +
+		`{"name":"d2","spaced":{"html":"\u003c\u0026\u003e","list":[1,2]}}]` + "\n" +
+		`{"generated":1}` + "\n"
+	if buf.String() != expect {
+		t.Fatalf("unexpected encoder output: %s", buf.String())
+	}
+
+	// 出错时不输出任何内容
+	buf.Reset()
+	if err := enc.Encode(map[string]interface{}{"ch": make(chan int)}); err == nil || buf.Len() != 0 {
+		t.Fatalf("expect error without output, got %v, %q", err, buf.String())
+	}
+}
+
+func TestSiftSlice(t *testing.T) {
+	type BadTag struct {
+		Name string `confidential:"level9"`
+	}
+	type Row struct {
+		Id     int         `json:"id"`
+		Secret string      `json:"secret" confidential:"level2"`
+		Any    interface{} `json:"any"`
+	}
+
+	rows := make([]*Row, 1000)
+	values := make([]Row, len(rows))
+	ifaces := make([]interface{}, len(rows))
+	for i := range rows {
+		if i%97 == 0 {
+			continue // nil 元素
+		}
+		rows[i] = &Row{Id: i, Secret: fmt.Sprint("s", i), Any: fmt.Sprint("any", i)}
+		values[i] = *rows[i]
+		ifaces[i] = rows[i]
+	}
+
+	for _, workers := range []int{0, 1, 3, 64} {
+		opts := &SiftSliceOptions{Workers: workers}
+		for _, items := range []interface{}{rows, values, ifaces, [2]Row{values[1], values[2]}} {
+			out, err := SiftSlice(context.Background(), items, CONFIDENTIAL_LEVEL1, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rv := reflect.ValueOf(items)
+			if len(out) != rv.Len() {
+				t.Fatalf("%d workers: unexpected result length %d", workers, len(out))
+			}
+			// 结果的顺序与输入的顺序一致
+			for i := range out {
+				var expect map[string]interface{}
+				if item := rv.Index(i); !(item.Kind() != reflect.Struct && item.IsNil()) {
+					if expect, err = SiftStruct(item.Interface(), CONFIDENTIAL_LEVEL1); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if !reflect.DeepEqual(out[i], expect) {
+					t.Fatalf("%d workers, item[%d]: %v vs %v", workers, i, out[i], expect)
+				}
+			}
+		}
+	}
+
+	// 返回下标最小的出错元素
+	rows[700].Any = BadTag{}
+	rows[300].Any = &BadTag{}
+	for _, workers := range []int{1, 4, 16} {
+		_, err := SiftSlice(context.Background(), rows, CONFIDENTIAL_LEVEL1, &SiftSliceOptions{Workers: workers})
+		var itemErr *SliceItemError
+		if !errors.As(err, &itemErr) || itemErr.Index != 300 {
+			t.Fatalf("%d workers: expect error of item[300], got %v", workers, err)
+		}
+	}
+
+	// 取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SiftSlice(ctx, values, CONFIDENTIAL_LEVEL1, nil); err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+	if _, err := SiftSlice(ctx, values[:0], CONFIDENTIAL_LEVEL1, nil); err != context.Canceled {
+		t.Fatalf("expect context.Canceled for empty slice, got %v", err)
+	}
+
+	if _, err := SiftSlice(context.Background(), []int{1}, CONFIDENTIAL_LEVEL1, nil); err == nil {
+		t.Fatal("expect error for non-struct elements")
+	}
+}
+
+// 记录刷新次数的 io.Writer
+type flushCounter struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+}
+
+func TestStreamWriter(t *testing.T) {
+	type Row struct {
+		Id     int         `json:"id"`
+		Secret string      `json:"secret" confidential:"level2"`
+		Any    interface{} `json:"any,omitempty"`
+	}
+
+	var expectArray, expectLines bytes.Buffer
+	expectArray.WriteByte('[')
+	for i := 0; i < 250; i++ {
+		b, err := Marshal(&Row{Id: i, Secret: "s"}, CONFIDENTIAL_LEVEL1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 {
+			expectArray.WriteByte(',')
+		}
+		expectArray.Write(b)
+		expectLines.Write(b)
+		expectLines.WriteByte('\n')
+	}
+	expectArray.WriteString("]\n")
+
+	// json 数组（channel）
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		for i := 0; i < 250; i++ {
+			ch <- &Row{Id: i, Secret: "s"}
+		}
+	}()
+	fc := &flushCounter{}
+	sw := NewStreamWriter(fc, CONFIDENTIAL_LEVEL1, STREAM_FORMAT_JSON_ARRAY)
+	if err := sw.WriteChan(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fc.String() != expectArray.String() || fc.flushes != 250/STREAM_FLUSH_INTERVAL+1 {
+		t.Fatalf("unexpected json array output (%d flushes): %s", fc.flushes, fc.String())
+	}
+
+	// NDJSON（迭代器）
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	sw = NewStreamWriter(bw, CONFIDENTIAL_LEVEL1, STREAM_FORMAT_NDJSON)
+	sw.SetFlushInterval(0)
+	err := sw.WriteSeq(func(yield func(interface{}) bool) {
+		for i := 0; i < 250; i++ {
+			if !yield(Row{Id: i, Secret: "s"}) {
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expectLines.String() {
+		t.Fatalf("unexpected ndjson output: %s", buf.String())
+	}
+
+	// 没有任何元素
+	buf.Reset()
+	sw = NewStreamWriter(&buf, CONFIDENTIAL_LEVEL1, STREAM_FORMAT_JSON_ARRAY)
+	if err := sw.Close(); err != nil || buf.String() != "[]\n" {
+		t.Fatalf("unexpected empty output: %q, %v", buf.String(), err)
+	}
+
+	// 出错的元素
+	type BadTag struct {
+		Name string `confidential:"level9"`
+	}
+	buf.Reset()
+	sw = NewStreamWriter(&buf, CONFIDENTIAL_LEVEL1, STREAM_FORMAT_NDJSON)
+	yielded := 0
+	err = sw.WriteSeq(func(yield func(interface{}) bool) {
+		for i := 0; i < 10; i++ {
+			yielded++
+			row := &Row{Id: i}
+			if i == 3 {
+				row.Any = BadTag{}
+			}
+			if !yield(row) {
+				return
+			}
+		}
+	})
+	var itemErr *SliceItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 3 || yielded != 4 || strings.Count(buf.String(), "\n") != 3 {
+		t.Fatalf("expect error of item[3], got %v (%d yielded): %s", err, yielded, buf.String())
+	}
+	if sw.Close() != err {
+		t.Fatal("expect the same error after failure")
+	}
+
+	// json 数组在出错之后不完整（出错的元素本身不会被写入）
+	buf.Reset()
+	sw = NewStreamWriter(&buf, CONFIDENTIAL_LEVEL1, STREAM_FORMAT_JSON_ARRAY)
+	sw.Write(&Row{Id: 1})
+	if err := sw.Write(&Row{Id: 2, Any: BadTag{}}); err == nil || sw.Close() != err {
+		t.Fatalf("expect error of item[1], got %v", err)
+	}
+	if buf.String() != `[{"id":1}` {
+		t.Fatalf("unexpected output after failure: %s", buf.String())
+	}
+
+	// 取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sw = NewStreamWriter(&buf, CONFIDENTIAL_LEVEL1, STREAM_FORMAT_NDJSON)
+	if err := sw.WriteChan(ctx, make(chan interface{})); err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+}
+
+func TestMarshalAllLevels(t *testing.T) {
+	type Leaf struct {
+		Name string `json:"name"`
+		Tags []int  `json:"tags,omitempty"`
+	}
+	type Node struct {
+		Id       int     `json:"id,string"`
+		Owner    string  `json:"owner" confidential:"level1"`
+		Children []*Node `json:"children,omitempty"`
+	}
+	type Event struct {
+		*auditInfo `confidential:"level1"`
+
+		Id      int                    `json:"id"`
+		Leaf    Leaf                   `json:"leaf"`
+		Secret  string                 `json:"secret,omitempty" confidential:"level3"`
+		Price   money                  `json:"price" confidential:"level2"`
+		Cards   []maskedCard           `json:"cards"`
+		CardMap map[string]maskedCard  `json:"card_map" confidential:"level1"`
+		Payload interface{}            `json:"payload"`
+		Tree    *Node                  `json:"tree"`
+		Extra   map[string]interface{} `json:"extra"`
+		Empty   interface{}            `json:"empty"`
+	}
+
+	tree := &Node{Id: 1, Owner: "root", Children: []*Node{{Id: 2, Owner: "a"}, {Id: 3}}}
+	events := []*Event{
+		{},
+		{
+			auditInfo: &auditInfo{Creator: "c", Version: 2},
+			Id:        1,
+			Leaf:      Leaf{Name: "<leaf>", Tags: []int{1, 2}},
+			Secret:    "s",
+			Price:     money{Cents: 1050, Currency: "USD"},
+			Cards:     []maskedCard{{Number: "12345678"}},
+			CardMap:   map[string]maskedCard{"x": {Number: "87654321"}},
+			Payload:   tree,
+			Tree:      tree,
+			Extra:     map[string]interface{}{"b": Leaf{Name: "b"}, "a": &Node{Id: 4, Owner: "o"}, "c": nil},
+		},
+	}
+
+	for i, e := range events {
+		all, err := MarshalAllLevels(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != CONFIDENTIAL_LEVEL_MAX+1 {
+			t.Fatalf("unexpected number of levels %d", len(all))
+		}
+		for level, b := range all {
+			expect, err := Marshal(e, level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, expect) {
+				t.Fatalf("event[%d] level %d: %s vs %s", i, level, b, expect)
+			}
+		}
+	}
+
+	// 值类型（不可寻址）
+	all, err := MarshalAllLevels(*events[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for level, b := range all {
+		if expect, _ := Marshal(*events[1], level); !bytes.Equal(b, expect) {
+			t.Fatalf("level %d: %s vs %s", level, b, expect)
+		}
+	}
+
+	// 出错
+	if _, err := MarshalAllLevels(Event{Payload: make(chan int)}); err == nil {
+		t.Fatal("expect error for unsupported type")
+	}
+}
+
+func TestSiftOrdered(t *testing.T) {
+	type Inner struct {
+		Zeta  string `json:"zeta"`
+		Alpha int    `json:"alpha" confidential:"level2"`
+	}
+	type Base struct {
+		Mid string `json:"mid" confidential:"level1"`
+	}
+	type Outer struct {
+		Z      int                    `json:"z"`
+		Base                          // 展开至当前层
+		Secret string                 `json:"secret" confidential:"level3"`
+		B      *Inner                 `json:"b"`
+		A      []Inner                `json:"a"`
+		Any    interface{}            `json:"any"`
+		M      map[string]interface{} `json:"m"`
+		Amount int64                  `json:"amount,string"`
+	}
+
+	o := &Outer{
+		Z:      1,
+		Base:   Base{Mid: "m"},
+		Secret: "s",
+		B:      &Inner{Zeta: "<z>", Alpha: 2},
+		A:      []Inner{{Zeta: "a0", Alpha: 3}},
+		Any:    Inner{Zeta: "any", Alpha: 4},
+		M:      map[string]interface{}{"y": Inner{Zeta: "y"}, "x": 1},
+		Amount: 100,
+	}
+
+	for level := CONFIDENTIAL_LEVEL0 - 1; level <= CONFIDENTIAL_LEVEL_MAX+1; level++ {
+		om, err := SiftOrdered(o, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(om)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect, err := Marshal(o, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, expect) {
+			t.Fatalf("level %d: %s vs %s", level, b, expect)
+		}
+	}
+
+	// 最高级别下与 json.Marshal() 一致
+	om, _ := SiftOrdered(o, CONFIDENTIAL_LEVEL_MAX)
+	b, _ := json.Marshal(om)
+	if expect, _ := json.Marshal(o); !bytes.Equal(b, expect) {
+		t.Fatalf("%s vs %s", b, expect)
+	}
+
+	om, _ = SiftOrdered(o, CONFIDENTIAL_LEVEL1)
+	if keys := om.Keys(); !reflect.DeepEqual(keys, []string{"z", "mid", "b", "a", "any", "m", "amount"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	if v, ok := om.Get("b"); !ok {
+		t.Fatal("expect key b")
+	} else if inner, ok := v.(OrderedMap); !ok || !reflect.DeepEqual(inner, OrderedMap{{Key: "zeta", Value: "<z>"}}) {
+		t.Fatalf("unexpected nested value %#v", v)
+	}
+	if _, ok := om.Get("secret"); ok {
+		t.Fatal("secret should be sifted out")
+	}
+	if m := om.Map(); len(m) != len(om) || m["z"] != 1 {
+		t.Fatalf("unexpected map %v", m)
+	}
+
+	if _, err := SiftOrdered([]int{1}, CONFIDENTIAL_LEVEL0); err == nil {
+		t.Fatal("expect error for invalid param type")
+	}
+}
+
+func TestSiftStructCopy(t *testing.T) {
+	type Inner struct {
+		Tags []string `json:"tags"`
+	}
+	type Record struct {
+		Ids    []int             `json:"ids"`
+		Attrs  map[string]string `json:"attrs"`
+		Count  *int              `json:"count"`
+		Inner  Inner             `json:"inner"`
+		Any    interface{}       `json:"any"`
+		Self   *[]int            `json:"self"`
+		Stamp  time.Time         `json:"stamp"`
+		Secret []byte            `json:"secret" confidential:"level2"`
+	}
+
+	count := 1
+	r := &Record{
+		Ids:    []int{1, 2},
+		Attrs:  map[string]string{"k": "v"},
+		Count:  &count,
+		Inner:  Inner{Tags: []string{"a"}},
+		Any:    map[string][]int{"x": {1}},
+		Stamp:  time.Unix(1, 0).UTC(),
+		Secret: []byte("s"),
+	}
+	r.Self = &r.Ids
+
+	before, err := SiftStruct(r, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := SiftStructCopy(r, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, before) {
+		t.Fatalf("%v vs %v", m, before)
+	}
+	if _, ok := m["secret"]; ok {
+		t.Fatal("secret should be sifted out")
+	}
+
+	// 修改结果不影响原对象
+	m["ids"].([]int)[0] = 100
+	m["attrs"].(map[string]string)["k"] = "changed"
+	*m["count"].(*int) = 100
+	m["inner"].(map[string]interface{})["tags"].([]string)[0] = "changed"
+	m["any"].(map[string][]int)["x"][0] = 100
+	if r.Ids[0] != 1 || r.Attrs["k"] != "v" || count != 1 || r.Inner.Tags[0] != "a" || r.Any.(map[string][]int)["x"][0] != 1 {
+		t.Fatalf("source mutated: %+v", r)
+	}
+
+	// 修改原对象不影响结果
+	r.Ids[1] = 200
+	if m["ids"].([]int)[1] != 2 || (*m["self"].(*[]int))[1] != 2 {
+		t.Fatalf("result mutated: %v", m)
+	}
+	if !m["stamp"].(time.Time).Equal(time.Unix(1, 0)) {
+		t.Fatalf("unexpected stamp %v", m["stamp"])
+	}
+
+	// 接口中的结构体依然被筛选（结果为新建的 map）
+	type Wrapper struct {
+		Payload interface{} `json:"payload"`
+	}
+	wm, err := SiftStructCopy(&Wrapper{Payload: r}, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := wm["payload"].(map[string]interface{})
+	if !reflect.DeepEqual(p["ids"], []int{1, 200}) {
+		t.Fatalf("unexpected payload %v", p)
+	}
+	r.Ids[0] = 300
+	if p["ids"].([]int)[0] != 1 {
+		t.Fatalf("result mutated: %v", p)
+	}
+
+	if _, err := SiftStructCopy(1, CONFIDENTIAL_LEVEL0); err == nil {
+		t.Fatal("expect error for invalid param type")
+	}
+}
+
+func TestSiftCopy(t *testing.T) {
+	type Contact struct {
+		Email string `json:"email" confidential:"level1"`
+		Phone string `json:"phone" confidential:"level2"`
+	}
+	type hidden struct {
+		Note string `json:"note"`
+	}
+	type Account struct {
+		Id       int                 `json:"id"`
+		Name     string              `json:"name"`
+		Password string              `json:"-" confidential:"level3"`
+		Contact  *Contact            `json:"contact"`
+		Backups  []Contact           `json:"backups"`
+		ByName   map[string]*Contact `json:"by_name"`
+		Extra    interface{}         `json:"extra"`
+		Tags     []string            `json:"tags" confidential:"level1"`
+		Owner    *Account            `json:"owner"`
+		*auditInfo
+		hidden  `confidential:"level2"`
+		secret  string `confidential:"level2"`
+		notes   []string
+		priv    *Contact
+		created time.Time
+	}
+
+	src := &Account{
+		Id:        1,
+		Name:      "n",
+		Password:  "p",
+		Contact:   &Contact{Email: "e", Phone: "p"},
+		Backups:   []Contact{{Email: "e1", Phone: "p1"}},
+		ByName:    map[string]*Contact{"x": {Email: "e2", Phone: "p2"}},
+		Extra:     Contact{Email: "e3", Phone: "p3"},
+		Tags:      []string{"t"},
+		auditInfo: &auditInfo{Creator: "c", Version: 1},
+		hidden:    hidden{Note: "h"},
+		secret:    "s",
+		notes:     []string{"n"},
+		priv:      &Contact{Email: "e4", Phone: "p4"},
+		created:   time.Unix(1, 0).In(time.Local),
+	}
+	src.Owner = src
+
+	out, err := SiftCopy(src, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out == src || out.Contact == src.Contact || &out.Backups[0] == &src.Backups[0] {
+		t.Fatal("expect a deep copy")
+	}
+	if out.Id != 1 || out.Name != "n" || out.Password != "" {
+		t.Fatalf("unexpected account %+v", out)
+	}
+	if *out.Contact != (Contact{Email: "e"}) || out.Backups[0] != (Contact{Email: "e1"}) || *out.ByName["x"] != (Contact{Email: "e2"}) {
+		t.Fatalf("unexpected contacts %+v %+v %+v", out.Contact, out.Backups, out.ByName["x"])
+	}
+	if out.Extra != (Contact{Email: "e3"}) || !reflect.DeepEqual(out.Tags, []string{"t"}) || out.Note != "" {
+		t.Fatalf("unexpected account %+v", out)
+	}
+	if out.Owner != out {
+		t.Fatal("expect the cycle to be preserved")
+	}
+	// 非公开的嵌入结构体指针同样被深拷贝并筛选
+	if out.auditInfo == src.auditInfo || *out.auditInfo != (auditInfo{Version: 1}) {
+		t.Fatalf("unexpected audit info %+v", out.auditInfo)
+	}
+	// 非公开的域同样被深拷贝并筛选（*time.Location 除外）
+	if out.secret != "" || &out.notes[0] == &src.notes[0] || out.notes[0] != "n" {
+		t.Fatalf("unexpected unexported fields %q %v", out.secret, out.notes)
+	}
+	if out.priv == src.priv || *out.priv != (Contact{Email: "e4"}) {
+		t.Fatalf("unexpected unexported pointer %+v", out.priv)
+	}
+	if !out.created.Equal(src.created) || out.created.Location() != time.Local {
+		t.Fatalf("unexpected time %v", out.created)
+	}
+	if c, _ := SiftCopy(src, CONFIDENTIAL_LEVEL0); *c.priv != (Contact{}) {
+		t.Fatalf("unexpected unexported pointer %+v", c.priv)
+	}
+	if c, _ := SiftCopy(src, CONFIDENTIAL_LEVEL2); c.secret != "s" || c.Note != "h" || c.auditInfo.Creator != "c" {
+		t.Fatalf("unexpected account %+v", c)
+	}
+
+	// 原对象不受影响
+	if src.Password != "p" || src.Tags[0] != "t" || src.Contact.Phone != "p" || src.Backups[0].Phone != "p1" || src.ByName["x"].Phone != "p2" || src.Note != "h" ||
+		src.secret != "s" || src.Creator != "c" || src.priv.Phone != "p4" {
+		t.Fatalf("source mutated: %+v", src)
+	}
+
+	// 拷贝在同一级别下的筛选结果不变
+	for level := CONFIDENTIAL_LEVEL0; level <= CONFIDENTIAL_LEVEL_MAX; level++ {
+		v := Account{Id: 2, Contact: &Contact{Email: "e", Phone: "p"}, Tags: []string{"t"}, hidden: hidden{Note: "h"}}
+		c, err := SiftCopy(v, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect, _ := Marshal(&v, level)
+		if b, _ := Marshal(&c, level); !bytes.Equal(b, expect) {
+			t.Fatalf("level %d: %s vs %s", level, b, expect)
+		}
+	}
+	if c, _ := SiftCopy(Account{Id: 3, Name: "n"}, CONFIDENTIAL_LEVEL0-1); c.Id != 0 || c.Name != "" {
+		t.Fatalf("expect all fields to be zeroed %+v", c)
+	}
+
+	// 任意类型
+	list, err := SiftCopy([]Contact{{Email: "e", Phone: "p"}}, CONFIDENTIAL_LEVEL0)
+	if err != nil || !reflect.DeepEqual(list, []Contact{{}}) {
+		t.Fatalf("unexpected list %v %v", list, err)
+	}
+	if v, err := SiftCopy[interface{}](nil, CONFIDENTIAL_LEVEL0); v != nil || err != nil {
+		t.Fatalf("unexpected result %v %v", v, err)
+	}
+
+	// 映射的键同样被筛选
+	type Key struct {
+		Id    int
+		Token string `confidential:"level2"`
+	}
+	keyed, err := SiftCopy(map[Key]int{{Id: 1, Token: "a"}: 1}, CONFIDENTIAL_LEVEL1)
+	if err != nil || !reflect.DeepEqual(keyed, map[Key]int{{Id: 1}: 1}) {
+		t.Fatalf("unexpected map %v %v", keyed, err)
+	}
+	if _, err := SiftCopy(map[Key]int{{Id: 1, Token: "tok-a"}: 1, {Id: 1, Token: "tok-b"}: 2}, CONFIDENTIAL_LEVEL1); err == nil || strings.Contains(err.Error(), "tok-") {
+		t.Fatalf("expect error for colliding keys, got %v", err)
+	}
+
+	type Bad struct {
+		A int `confidential:"level9"`
+	}
+	if _, err := SiftCopy([]Bad{{}}, CONFIDENTIAL_LEVEL0); err == nil {
+		t.Fatal("expect error for invalid confidential tag")
+	}
+}
+
+func TestFor(t *testing.T) {
+	type Profile struct {
+		Name  string     `json:"name"`
+		Email string     `json:"email" confidential:"level1"`
+		Card  maskedCard `json:"card"`
+		*auditInfo
+	}
+
+	p := Profile{Name: "n", Email: "e", Card: maskedCard{Number: "12345678"}, auditInfo: &auditInfo{Creator: "c", Version: 1}}
+
+	ps, err := For[*Profile]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs := MustFor[Profile]()
+	if ps.Type() != reflect.TypeOf(p) || vs.Type() != reflect.TypeOf(p) {
+		t.Fatalf("unexpected type %v %v", ps.Type(), vs.Type())
+	}
+
+	for level := CONFIDENTIAL_LEVEL0 - 1; level <= CONFIDENTIAL_LEVEL_MAX+1; level++ {
+		expect, _ := SiftStruct(&p, level)
+		m1, err1 := ps.Sift(&p, level)
+		m2, err2 := vs.Sift(p, level)
+		if err1 != nil || err2 != nil || !reflect.DeepEqual(m1, expect) || !reflect.DeepEqual(m2, expect) {
+			t.Fatalf("level %d: %v %v vs %v", level, m1, m2, expect)
+		}
+
+		expectB, _ := Marshal(&p, level)
+		b1, err1 := ps.Marshal(&p, level)
+		b2, err2 := vs.Marshal(p, level)
+		if err1 != nil || err2 != nil || !bytes.Equal(b1, expectB) || !bytes.Equal(b2, expectB) {
+			t.Fatalf("level %d: %s %s vs %s", level, b1, b2, expectB)
+		}
+
+		om, _ := vs.SiftOrdered(p, level)
+		if keys := vs.Fields(level); !reflect.DeepEqual(keys, om.Keys()) {
+			t.Fatalf("level %d: %v vs %v", level, keys, om.Keys())
+		}
+
+		expectX, _ := MarshalXML(&p, level)
+		x1, err1 := ps.MarshalXMLLevel(&p, level)
+		x2, err2 := vs.MarshalXMLLevel(p, level)
+		if err1 != nil || err2 != nil || len(expectX) == 0 || !bytes.Equal(x1, expectX) || !bytes.Equal(x2, expectX) {
+			t.Fatalf("level %d: %s %s vs %s", level, x1, x2, expectX)
+		}
+	}
+
+	if fields := ps.Fields(CONFIDENTIAL_LEVEL1); !reflect.DeepEqual(fields, []string{"name", "email", "card", "version"}) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+	if fields := ps.Fields(CONFIDENTIAL_LEVEL_MAX); !reflect.DeepEqual(fields, []string{"name", "email", "card", "creator", "version"}) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	all, err := vs.MarshalAllLevels(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for level, b := range all {
+		if expect, _ := Marshal(&p, level); !bytes.Equal(b, expect) {
+			t.Fatalf("level %d: %s vs %s", level, b, expect)
+		}
+	}
+
+	c, err := vs.SiftCopy(p, CONFIDENTIAL_LEVEL0)
+	if err != nil || c.Email != "" || c.Name != "n" {
+		t.Fatalf("unexpected copy %+v %v", c, err)
+	}
+	cm, err := ps.SiftStructCopy(&p, CONFIDENTIAL_LEVEL1)
+	if expect, _ := SiftStruct(&p, CONFIDENTIAL_LEVEL1); err != nil || !reflect.DeepEqual(cm, expect) {
+		t.Fatalf("unexpected result %v %v", cm, err)
+	}
+
+	// nil 指针
+	if m, err := ps.Sift(nil, CONFIDENTIAL_LEVEL0); m != nil || err != nil {
+		t.Fatalf("unexpected result %v %v", m, err)
+	}
+	if b, err := ps.Marshal(nil, CONFIDENTIAL_LEVEL0); string(b) != "null" || err != nil {
+		t.Fatalf("unexpected result %s %v", b, err)
+	}
+	if b, err := ps.MarshalXMLLevel(nil, CONFIDENTIAL_LEVEL0); len(b) != 0 || err != nil {
+		t.Fatalf("unexpected result %s %v", b, err)
+	}
+
+	// encoder 缓存被清空之后依然可以使用
+	RejectUnregistered(true)
+	RejectUnregistered(false)
+	if b, err := ps.Marshal(&p, CONFIDENTIAL_LEVEL1); err != nil || string(b) != `{"name":"n","email":"e","card":"****5678","version":1}` {
+		t.Fatalf("unexpected result %s %v", b, err)
+	}
+
+	// 除了输出的结果之外不再分配内存
+	type Plain struct {
+		Name   string `json:"name"`
+		Secret string `json:"secret" confidential:"level2"`
+	}
+	plain, hs := &Plain{Name: "n", Secret: "s"}, MustFor[*Plain]()
+	if n := testing.AllocsPerRun(100, func() { hs.Marshal(plain, CONFIDENTIAL_LEVEL1) }); n > 1 {
+		t.Fatalf("Marshal() allocates %v times per run", n)
+	}
+
+	// 生成的方法
+	gs := MustFor[generatedStub]()
+	if m, _ := gs.Sift(generatedStub{}, CONFIDENTIAL_LEVEL1); m["generated"] != CONFIDENTIAL_LEVEL1 {
+		t.Fatalf("expect generated method to be used: %v", m)
+	}
+	if b, _ := gs.Marshal(generatedStub{}, CONFIDENTIAL_LEVEL2); string(b) != `{"generated":2}` {
+		t.Fatalf("expect generated method to be used: %s", b)
+	}
+
+	// 非结构体
+	if _, err := For[int](); err == nil {
+		t.Fatal("expect error for invalid param type")
+	}
+	if _, err := For[**Profile](); err == nil {
+		t.Fatal("expect error for invalid param type")
+	}
+	type Bad struct {
+		A int `confidential:"level9"`
+	}
+	if _, err := For[Bad](); err == nil {
+		t.Fatal("expect error for invalid confidential tag")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expect panic")
+			}
+		}()
+		MustFor[[]int]()
+	}()
+}
+
+func TestAnyRootValue(t *testing.T) {
+	type User struct {
+		Name  string `json:"name"`
+		Email string `json:"email" confidential:"level1"`
+	}
+
+	users := []*User{{Name: "a", Email: "a@x"}, nil, {Name: "b", Email: "b@x"}}
+	byId := map[string]User{"1": {Name: "a", Email: "a@x"}}
+
+	cases := []struct {
+		v      interface{}
+		sifted interface{}
+		json   string
+	}{
+		{nil, nil, `null`},
+		{(*User)(nil), nil, `null`},
+		{(*generatedStub)(nil), nil, `null`},
+		{users, []interface{}{map[string]interface{}{"name": "a"}, nil, map[string]interface{}{"name": "b"}}, `[{"name":"a"},null,{"name":"b"}]`},
+		{byId, map[string]interface{}{"1": map[string]interface{}{"name": "a"}}, `{"1":{"name":"a"}}`},
+		{[2]interface{}{User{Name: "c", Email: "c@x"}, 1}, []interface{}{map[string]interface{}{"name": "c"}, 1}, `[{"name":"c"},1]`},
+		{[]int{1, 2}, []int{1, 2}, `[1,2]`},
+		{"<s>", "<s>", `"\u003cs\u003e"`},
+	}
+	for i, c := range cases {
+		v, err := Sift(c.v, CONFIDENTIAL_LEVEL0)
+		if err != nil || !reflect.DeepEqual(v, c.sifted) {
+			t.Fatalf("case[%d]: Sift() = %#v, %v", i, v, err)
+		}
+		b, err := Marshal(c.v, CONFIDENTIAL_LEVEL0)
+		if err != nil || string(b) != c.json {
+			t.Fatalf("case[%d]: Marshal() = %s, %v", i, b, err)
+		}
+		all, err := MarshalAllLevels(c.v)
+		if err != nil || string(all[CONFIDENTIAL_LEVEL0]) != c.json {
+			t.Fatalf("case[%d]: MarshalAllLevels() = %s, %v", i, all, err)
+		}
+	}
+
+	// 生成的方法
+	if v, err := Sift(&generatedStub{}, CONFIDENTIAL_LEVEL1); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"generated": CONFIDENTIAL_LEVEL1}) {
+		t.Fatalf("Sift() = %v, %v", v, err)
+	}
+
+	// nil 指针不再 panic
+	for _, f := range []func(interface{}, int) (interface{}, error){
+		func(s interface{}, level int) (interface{}, error) { return SiftStruct(s, level) },
+		func(s interface{}, level int) (interface{}, error) { return SiftStructCopy(s, level) },
+		func(s interface{}, level int) (interface{}, error) { return SiftOrdered(s, level) },
+	} {
+		for _, s := range []interface{}{nil, (*User)(nil), (*generatedStub)(nil)} {
+			if v, err := f(s, CONFIDENTIAL_LEVEL0); err != nil || !reflect.ValueOf(v).IsNil() {
+				t.Fatalf("unexpected result %v, %v", v, err)
+			}
+		}
+	}
+
+	// 映射依然可以通过 SiftStruct() 筛选，切片则不行
+	if m, err := SiftStruct(byId, CONFIDENTIAL_LEVEL1); err != nil || !reflect.DeepEqual(m, map[string]interface{}{"1": map[string]interface{}{"name": "a", "email": "a@x"}}) {
+		t.Fatalf("SiftStruct() = %v, %v", m, err)
+	}
+	if _, err := SiftStruct(users, CONFIDENTIAL_LEVEL1); err == nil || !strings.Contains(err.Error(), "slice") {
+		t.Fatalf("expect error for slice, got %v", err)
+	}
+
+	// 映射中的值筛选出错时返回真实的错误
+	type BadTag struct {
+		Name string `confidential:"level9"`
+	}
+	if _, err := SiftStruct(map[string]interface{}{"bad": BadTag{}}, CONFIDENTIAL_LEVEL1); err == nil || !strings.Contains(err.Error(), "level9") {
+		t.Fatalf("expect confidential tag error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, CONFIDENTIAL_LEVEL0).Encode((*generatedStub)(nil)); err != nil || buf.String() != "null\n" {
+		t.Fatalf("Encode() = %q, %v", buf.String(), err)
+	}
+}
+
+// 测试用的 MessagePack 解码（只支持 MarshalMsgpack() 输出的格式；timestamp 解码为 time.Time，bin 解码为 []byte）
+func decodeMsgpack(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("unexpected end of input")
+	}
+	uintN := func(b []byte, n int) uint64 {
+		var v uint64
+		for i := 0; i < n; i++ {
+			v = v<<8 | uint64(b[i])
+		}
+		return v
+	}
+	c, b := b[0], b[1:]
+	var n int
+	switch {
+	case c <= 0x7f:
+		return int64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+		return string(b[:n]), b[n:], nil
+	case c&0xf0 == 0x90, c == 0xdc, c == 0xdd:
+		switch c {
+		case 0xdc:
+			n, b = int(uintN(b, 2)), b[2:]
+		case 0xdd:
+			n, b = int(uintN(b, 4)), b[4:]
+		default:
+			n = int(c & 0x0f)
+		}
+		out := make([]interface{}, n)
+		for i := range out {
+			var err error
+			if out[i], b, err = decodeMsgpack(b); err != nil {
+				return nil, nil, err
+			}
+		}
+		return out, b, nil
+	case c&0xf0 == 0x80, c == 0xde, c == 0xdf:
+		switch c {
+		case 0xde:
+			n, b = int(uintN(b, 2)), b[2:]
+		case 0xdf:
+			n, b = int(uintN(b, 4)), b[4:]
+		default:
+			n = int(c & 0x0f)
+		}
+		out := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k, rest, err := decodeMsgpack(b)
+			if err != nil {
+				return nil, nil, err
+			}
+			if out[k.(string)], b, err = decodeMsgpack(rest); err != nil {
+				return nil, nil, err
+			}
+		}
+		return out, b, nil
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, b, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n = 1 << (c - 0xcc)
+		return uintN(b, n), b[n:], nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n = 1 << (c - 0xd0)
+		v := uintN(b, n) << (64 - 8*n)
+		return int64(v) >> (64 - 8*n), b[n:], nil
+	case 0xca:
+		return float64(math.Float32frombits(uint32(uintN(b, 4)))), b[4:], nil
+	case 0xcb:
+		return math.Float64frombits(uintN(b, 8)), b[8:], nil
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		l := map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4, 0xc4: 1, 0xc5: 2, 0xc6: 4}[c]
+		n, b = int(uintN(b, l)), b[l:]
+		if c >= 0xd9 {
+			return string(b[:n]), b[n:], nil
+		}
+		return append([]byte{}, b[:n]...), b[n:], nil
+	case 0xd6:
+		return time.Unix(int64(uintN(b[1:], 4)), 0).UTC(), b[5:], nil
+	case 0xd7:
+		v := uintN(b[1:], 8)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)).UTC(), b[9:], nil
+	case 0xc7:
+		return time.Unix(int64(uintN(b[6:], 8)), int64(uintN(b[2:], 4))).UTC(), b[14:], nil
+	}
+	return nil, nil, fmt.Errorf("unsupported format 0x%x", c)
+}
+
+// 将 json 或者解码之后的 MessagePack 统一为 json.Unmarshal() 的结果以便比较
+func normalizeJson(t *testing.T, v interface{}) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestMarshalMsgpack(t *testing.T) {
+	type Small struct {
+		A int    `json:"a"`
+		B string `json:"b" confidential:"level1"`
+	}
+	b, err := MarshalMsgpack(&Small{A: 1, B: "x"}, CONFIDENTIAL_LEVEL0)
+	if err != nil || !bytes.Equal(b, []byte{0x81, 0xa1, 'a', 0x01}) {
+		t.Fatalf("unexpected output % x, %v", b, err)
+	}
+
+	type Record struct {
+		*auditInfo
+		Id      int64                  `json:"id"`
+		Neg     int                    `json:"neg"`
+		Big     uint64                 `json:"big"`
+		Ratio   float64                `json:"ratio"`
+		Small   float32                `json:"small"`
+		Ok      bool                   `json:"ok"`
+		Name    string                 `json:"name,omitempty"`
+		Long    string                 `json:"long" confidential:"level1"`
+		Raw     []byte                 `json:"raw" confidential:"level2"`
+		Ids     []int                  `json:"ids"`
+		Cards   []maskedCard           `json:"cards"`
+		Price   money                  `json:"price"`
+		When    time.Time              `json:"when"`
+		Extra   map[string]interface{} `json:"extra"`
+		Payload interface{}            `json:"payload"`
+		Nested  *Small                 `json:"nested"`
+		Num     json.Number            `json:"num"`
+		Skip    string                 `json:"-"`
+	}
+	r := &Record{
+		auditInfo: &auditInfo{Creator: "c", Version: 3},
+		Id:        1 << 40,
+		Neg:       -200,
+		Big:       math.MaxUint64,
+		Ratio:     0.25,
+		Small:     1.5,
+		Ok:        true,
+		Long:      strings.Repeat("long", 20),
+		Raw:       []byte{1, 2, 3},
+		Ids:       []int{-1, 0, 1 << 20},
+		Cards:     []maskedCard{{Number: "12345678"}},
+		Price:     money{Cents: 1050, Currency: "USD"},
+		When:      time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Extra:     map[string]interface{}{"b": Small{A: 2, B: "y"}, "a": []interface{}{nil, "s"}},
+		Payload:   &Small{A: 3, B: "z"},
+		Num:       "12.5",
+		Skip:      "skip",
+	}
+
+	for level := CONFIDENTIAL_LEVEL0 - 1; level <= CONFIDENTIAL_LEVEL_MAX+1; level++ {
+		b, err := MarshalMsgpack(r, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, rest, err := decodeMsgpack(b)
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("level %d: invalid msgpack % x: %v", level, b, err)
+		}
+		expect, err := Marshal(r, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := normalizeJson(t, v), normalizeJson(t, expect); !reflect.DeepEqual(got, want) {
+			t.Fatalf("level %d: %v vs %v", level, got, want)
+		}
+	}
+
+	// msgpack 标签优先于 json 标签
+	type Tagged struct {
+		Name   string `json:"name" msgpack:"n"`
+		Empty  string `json:"empty" msgpack:",omitempty"`
+		Hidden string `json:"hidden" msgpack:"-"`
+		Secret string `json:"secret" msgpack:"s" confidential:"level2"`
+		Zero   int    `json:"zero,omitempty" msgpack:"zero"`
+	}
+	b, err = MarshalMsgpack(Tagged{Name: "a", Hidden: "h", Secret: "x"}, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _, _ := decodeMsgpack(b)
+	if !reflect.DeepEqual(v, map[string]interface{}{"n": "a", "zero": int64(0)}) {
+		t.Fatalf("unexpected tagged output %v", v)
+	}
+
+	// msgpack 标签重命名之后的同名键：与 json 相同的规则（层次较浅的优先，同一层次中设置了标签的优先，无法区分的全部忽略）
+	type Inner struct {
+		Id   int    `msgpack:"id"`
+		Name string `msgpack:"name"`
+	}
+	type Conflict struct {
+		Inner
+		Name  string `json:"name"`
+		Plain int
+		Alias int    `msgpack:"Plain"`
+		A     string `json:"a"`
+		B     string `json:"b" msgpack:"a"`
+	}
+	b, err = MarshalMsgpack(Conflict{Inner: Inner{Id: 1, Name: "inner"}, Name: "outer", Plain: 2, Alias: 3, A: "a", B: "b"}, CONFIDENTIAL_LEVEL0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _, _ = decodeMsgpack(b)
+	if b[0] != 0x83 || !reflect.DeepEqual(v, map[string]interface{}{"id": int64(1), "name": "outer", "Plain": int64(3)}) {
+		t.Fatalf("unexpected conflict output % x: %v", b, v)
+	}
+
+	// 域的选择同样优先使用 msgpack 标签：`json:"-"` 的域依然输出，设置了别名的嵌入结构体嵌套输出
+	type Base struct {
+		Id     int `msgpack:"id"`
+		Secret int `msgpack:"secret" confidential:"level2"`
+	}
+	type Tagged2 struct {
+		Base   `msgpack:"base"`
+		Hidden string `json:"-" msgpack:"hidden"`
+		Gone   string `json:"gone" msgpack:"-"`
+	}
+	b, err = MarshalMsgpack(Tagged2{Base: Base{Id: 1, Secret: 2}, Hidden: "h", Gone: "g"}, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _, _ = decodeMsgpack(b)
+	if !reflect.DeepEqual(v, map[string]interface{}{"base": map[string]interface{}{"id": int64(1)}, "hidden": "h"}) {
+		t.Fatalf("unexpected tagged output % x: %v", b, v)
+	}
+	type Flat struct {
+		Base
+		Hidden string `json:"-" msgpack:"hidden"`
+	}
+	b, _ = MarshalMsgpack(Flat{Base: Base{Id: 1, Secret: 2}, Hidden: "h"}, CONFIDENTIAL_LEVEL_MAX)
+	v, _, _ = decodeMsgpack(b)
+	if !reflect.DeepEqual(v, map[string]interface{}{"id": int64(1), "secret": int64(2), "hidden": "h"}) {
+		t.Fatalf("unexpected flat output % x: %v", b, v)
+	}
+
+	// 任意的根节点
+	for _, c := range []struct {
+		v      interface{}
+		expect []byte
+	}{
+		{nil, []byte{0xc0}},
+		{(*Small)(nil), []byte{0xc0}},
+		{[]Small{{A: 1}}, []byte{0x91, 0x81, 0xa1, 'a', 0x01}},
+		{map[int]bool{2: true, 1: false}, []byte{0x82, 0xa1, '1', 0xc2, 0xa1, '2', 0xc3}},
+		{int64(-33), []byte{0xd0, 0xdf}},
+		{uint16(300), []byte{0xcd, 0x01, 0x2c}},
+		{time.Unix(1, 0), []byte{0xd6, 0xff, 0, 0, 0, 1}},
+	} {
+		if b, err := MarshalMsgpack(c.v, CONFIDENTIAL_LEVEL0); err != nil || !bytes.Equal(b, c.expect) {
+			t.Fatalf("%#v: unexpected output % x, %v", c.v, b, err)
+		}
+	}
+
+	if b, err := MustFor[Small]().MarshalMsgpackLevel(Small{A: 1}, CONFIDENTIAL_LEVEL0); err != nil || !bytes.Equal(b, []byte{0x81, 0xa1, 'a', 0x01}) {
+		t.Fatalf("unexpected output % x, %v", b, err)
+	}
+
+	if _, err := MarshalMsgpack(struct{ C chan int }{}, CONFIDENTIAL_LEVEL0); err == nil {
+		t.Fatal("expect error for unsupported type")
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	type Port struct {
+		Name string `yaml:"name"`
+		Num  int    `yaml:"num"`
+	}
+	type Network struct {
+		Gateway string `yaml:"gateway" confidential:"level1"`
+		Mask    string `yaml:"mask"`
+	}
+	type Config struct {
+		*auditInfo
+		Id       int               `json:"id"`
+		Host     string            `json:"host" yaml:"hostname"`
+		Password string            `yaml:"password" confidential:"level3"`
+		Note     string            `yaml:"note,omitempty"`
+		Empty    string            `json:"empty,omitempty"`
+		Hidden   string            `yaml:"-"`
+		Network  Network           `yaml:",inline"`
+		Labels   map[string]string `yaml:",inline"`
+		Ports    []Port            `yaml:"ports"`
+		Flow     []int             `yaml:"flow,flow"`
+		FlowPort Port              `yaml:"flow_port,flow" confidential:"level1"`
+		Nested   [][]string        `yaml:"nested"`
+		Ratio    float64           `yaml:"ratio"`
+		Raw      []byte            `yaml:"raw"`
+		When     time.Time         `yaml:"when"`
+		Texts    []string          `yaml:"texts"`
+		None     *Port             `yaml:"none"`
+		Any      interface{}       `yaml:"any"`
+		Nothing  []int             `yaml:"nothing"`
+	}
+
+	c := &Config{
+		auditInfo: &auditInfo{Creator: "c", Version: 2},
+		Id:        1,
+		Host:      "dev-01",
+		Password:  "p",
+		Hidden:    "h",
+		Network:   Network{Gateway: "10.0.0.1", Mask: "255.0.0.0"},
+		Labels:    map[string]string{"zone": "a", "env": "prod"},
+		Ports:     []Port{{Name: "http", Num: 80}, {Name: "https", Num: 443}},
+		Flow:      []int{1, 2},
+		FlowPort:  Port{Name: "ssh", Num: 22},
+		Nested:    [][]string{{"a", "b"}, {}},
+		Ratio:     1,
+		Raw:       []byte("hi"),
+		When:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Texts:     []string{"", "true", "1.5", "a: b", "- x", "line\nbreak", `q"uote`, "plain text", "#tag"},
+		Any:       map[string]interface{}{"k": []interface{}{}},
+		Nothing:   []int{},
+	}
+
+	b, err := MarshalYAML(c, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `version: 2
+id: 1
+hostname: dev-01
+gateway: "10.0.0.1"
+mask: "255.0.0.0"
+env: prod
+zone: a
+ports:
+  - name: http
+    num: 80
+  - name: https
+    num: 443
+flow: [1, 2]
+flow_port: {name: ssh, num: 22}
+nested:
+  - - a
+    - b
+  - []
+ratio: 1.0
+raw: !!binary aGk=
+when: 2024-01-02T03:04:05Z
+texts:
+  - ""
+  - "true"
+  - "1.5"
+  - "a: b"
+  - "- x"
+  - "line\nbreak"
+  - q"uote
+  - plain text
+  - "#tag"
+none: null
+any:
+  k: []
+nothing: []
+`
+	if string(b) != expect {
+		t.Fatalf("unexpected yaml:\n%s", b)
+	}
+
+	// 低级别下受限的域被筛除
+	b, _ = MarshalYAML(c, CONFIDENTIAL_LEVEL0)
+	for _, hidden := range []string{"gateway", "flow_port", "creator", "password"} {
+		if strings.Contains(string(b), hidden+":") {
+			t.Fatalf("%s should be sifted out:\n%s", hidden, b)
+		}
+	}
+	b, _ = MarshalYAML(c, CONFIDENTIAL_LEVEL_MAX)
+	if !strings.HasPrefix(string(b), "creator: c\nversion: 2\n") || !strings.Contains(string(b), "password: p\n") {
+		t.Fatalf("unexpected yaml:\n%s", b)
+	}
+
+	for _, c := range []struct {
+		v      interface{}
+		expect string
+	}{
+		{nil, "null\n"},
+		{(*Port)(nil), "null\n"},
+		{[]Port{}, "[]\n"},
+		{struct{}{}, "{}\n"},
+		{"yes", "\"yes\"\n"},
+		{map[int][]int{1: {2}}, "\"1\":\n  - 2\n"},
+	} {
+		if b, err := MarshalYAML(c.v, CONFIDENTIAL_LEVEL0); err != nil || string(b) != c.expect {
+			t.Fatalf("%#v: unexpected yaml %q, %v", c.v, b, err)
+		}
+	}
+	if b, err := MustFor[Port]().MarshalYAMLLevel(Port{Name: "a"}, CONFIDENTIAL_LEVEL0); err != nil || string(b) != "name: a\nnum: 0\n" {
+		t.Fatalf("unexpected yaml %q, %v", b, err)
+	}
+
+	type BadInline struct {
+		N int `yaml:",inline"`
+	}
+	if _, err := MarshalYAML(BadInline{}, CONFIDENTIAL_LEVEL0); err == nil {
+		t.Fatal("expect error for inline non-struct field")
+	}
+
+	// yaml 标签重命名之后的同名键：与 json 相同的规则
+	type Inner struct {
+		Id   int    `yaml:"id"`
+		Name string `yaml:"name"`
+	}
+	type Conflict struct {
+		Inner
+		Name  string `json:"name"`
+		Plain int
+		Alias int    `yaml:"Plain"`
+		A     string `json:"a"`
+		B     string `json:"b" yaml:"a"`
+	}
+	b, err = MarshalYAML(Conflict{Inner: Inner{Id: 1, Name: "inner"}, Name: "outer", Plain: 2, Alias: 3, A: "a", B: "b"}, CONFIDENTIAL_LEVEL0)
+	if err != nil || string(b) != "id: 1\nname: outer\nPlain: 3\n" {
+		t.Fatalf("unexpected yaml %q, %v", b, err)
+	}
+
+	// 展开之后的键重复时返回错误
+	type DupStruct struct {
+		Name string `yaml:"name"`
+		Port Port   `yaml:",inline"`
+	}
+	type DupMap struct {
+		Labels map[string]string `yaml:",inline"`
+		Zone   string            `yaml:"zone"`
+	}
+	for _, v := range []interface{}{DupStruct{Name: "a"}, DupMap{Labels: map[string]string{"zone": "a"}}} {
+		if b, err := MarshalYAML(v, CONFIDENTIAL_LEVEL0); err == nil {
+			t.Fatalf("%T: expect duplicated key error, got %q", v, b)
+		}
+	}
+	if b, err := MarshalYAML(DupMap{Labels: map[string]string{"env": "a"}}, CONFIDENTIAL_LEVEL0); err != nil || string(b) != "env: a\nzone: \"\"\n" {
+		t.Fatalf("unexpected yaml %q, %v", b, err)
+	}
+
+	// yaml 的 omitempty：所有公开域均为空值的结构体、IsZero() 为 true 的值同样被忽略（json 的 omitempty 则不会）
+	type Omit struct {
+		Port Port      `yaml:"port,omitempty" json:"port,omitempty"`
+		When time.Time `yaml:"when,omitempty" json:"when,omitempty"`
+		Arr  [1]int    `yaml:"arr,omitempty" json:"arr,omitempty"`
+	}
+	if b, err := MarshalYAML(Omit{}, CONFIDENTIAL_LEVEL0); err != nil || string(b) != "arr:\n  - 0\n" {
+		t.Fatalf("unexpected yaml %q, %v", b, err)
+	}
+	if b, err := MarshalYAML(Omit{Port: Port{Num: 1}}, CONFIDENTIAL_LEVEL0); err != nil || !strings.HasPrefix(string(b), "port:\n  name: \"\"\n  num: 1\n") {
+		t.Fatalf("unexpected yaml %q, %v", b, err)
+	}
+}
+
+func TestMarshalXML(t *testing.T) {
+	type Port struct {
+		Proto string `xml:"proto,attr"`
+		Num   int    `xml:",chardata"`
+	}
+	type Device struct {
+		XMLName  xml.Name  `xml:"device"`
+		Id       int       `xml:"id,attr"`
+		Serial   string    `xml:"serial,attr" confidential:"level2"`
+		Name     string    `xml:"name"`
+		Gateway  string    `xml:"net>gateway" confidential:"level2"`
+		Mask     string    `xml:"net>mask"`
+		Owner    string    `xml:"owner>name" confidential:"level1"`
+		Note     string    `xml:"note,omitempty"`
+		Hidden   string    `xml:"-"`
+		Ports    []Port    `xml:"ports>port"`
+		Extra    string    `xml:",innerxml"`
+		Secret   string    `xml:"secret" confidential:"level3"`
+		Comment  string    `xml:",comment"`
+		When     time.Time `xml:"when"`
+		Tags     []string  `xml:"tag"`
+		Password *string   `xml:"password,omitempty" confidential:"level2"`
+	}
+
+	password := "p<&>"
+	d := &Device{
+		Id:       7,
+		Serial:   "SN-1",
+		Name:     `a<b>&"c"`,
+		Gateway:  "10.0.0.1",
+		Mask:     "255.0.0.0",
+		Owner:    "o",
+		Hidden:   "h",
+		Ports:    []Port{{Proto: "tcp", Num: 80}, {Proto: "udp", Num: 53}},
+		Extra:    "<raw/>",
+		Secret:   "s",
+		Comment:  "c",
+		When:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tags:     []string{"x", "y"},
+		Password: &password,
+	}
+
+	// 最高级别下与 encoding/xml 的输出一致
+	b, err := MarshalXML(d, CONFIDENTIAL_LEVEL_MAX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, err := xml.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(expect) {
+		t.Fatalf("unexpected xml:\n%s\n%s", b, expect)
+	}
+
+	// level1 的合作方不会得到 level2 的元素以及属性
+	b, err = MarshalXML(d, CONFIDENTIAL_LEVEL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []byte(`<device id="7"><name>a&lt;b&gt;&amp;&#34;c&#34;</name><net><mask>255.0.0.0</mask></net>` +
+		`<owner><name>o</name></owner><ports><port proto="tcp">80</port><port proto="udp">53</port><raw/></ports>` +
+		`<!--c--><when>2024-01-02T03:04:05Z</when><tag>x</tag><tag>y</tag></device>`)
+	if string(b) != string(expect) {
+		t.Fatalf("unexpected xml:\n%s", b)
+	}
+	b, _ = MarshalXML(d, CONFIDENTIAL_LEVEL0)
+	for _, hidden := range []string{"serial=", "<gateway>", "<owner>", "<password>", "<secret>"} {
+		if strings.Contains(string(b), hidden) {
+			t.Fatalf("%s should be sifted out:\n%s", hidden, b)
+		}
+	}
+
+	// 嵌入的结构体与 omitempty
+	type Wrapper struct {
+		*auditInfo
+		Device *Device
+		Empty  string `xml:"empty,omitempty"`
+		None   *Port  `xml:"none"`
+		Any    interface{}
+	}
+	w := Wrapper{auditInfo: &auditInfo{Creator: "c", Version: 2}, Device: &Device{Id: 1}, Any: 1.5}
+	b, err = MarshalXML(w, CONFIDENTIAL_LEVEL0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []byte(`<Wrapper><Version>2</Version><device id="1"><name></name><net><mask></mask></net><ports></ports>` +
+		`<when>0001-01-01T00:00:00Z</when></device><Any>1.5</Any></Wrapper>`)
+	if string(b) != string(expect) {
+		t.Fatalf("unexpected xml:\n%s", b)
+	}
+
+	// 域的选择以及命名与 encoding/xml 一致（与 json 标签无关）
+	type Inner struct {
+		XMLName xml.Name `xml:"inner_tag"`
+		V       int      `xml:"v"`
+	}
+	type Valued struct {
+		XMLName xml.Name
+		V       int `xml:"v"`
+	}
+	type Left struct {
+		Name string `json:"name"`
+	}
+	type Right struct {
+		Title string `json:"name"`
+	}
+	type Deep struct {
+		Over string `xml:"over"`
+		Kept string `xml:"kept"`
+	}
+	type Named struct {
+		In   Inner
+		Ptr  *Valued
+		Dash string `json:"-" xml:"dash"`
+		Ns   string `xml:"urn:x ns"`
+		Left
+		Right
+		Deep
+		Over   string `xml:"over"`
+		Secret string `xml:"secret" confidential:"level2"`
+	}
+	n := Named{In: Inner{V: 1}, Ptr: &Valued{XMLName: xml.Name{Local: "valued"}, V: 2}, Dash: "d", Ns: "n",
+		Left: Left{Name: "l"}, Right: Right{Title: "r"}, Deep: Deep{Over: "deep", Kept: "k"}, Over: "o", Secret: "s"}
+	b, err = MarshalXML(n, CONFIDENTIAL_LEVEL_MAX)
+	if expect, _ := xml.Marshal(n); err != nil || string(b) != string(expect) {
+		t.Fatalf("unexpected xml %v:\n%s\n%s", err, b, expect)
+	}
+	b, _ = MarshalXML(n, CONFIDENTIAL_LEVEL1)
+	if expect, _ := xml.Marshal(n); string(b) != strings.Replace(string(expect), "<secret>s</secret>", "", 1) {
+		t.Fatalf("unexpected xml:\n%s", b)
+	}
+	if !strings.Contains(string(b), "<inner_tag><v>1</v></inner_tag><valued><v>2</v></valued>") || !strings.Contains(string(b), `<ns xmlns="urn:x">n</ns>`) {
+		t.Fatalf("unexpected xml:\n%s", b)
+	}
+
+	// 与 encoding/xml 一样无法处理的标签返回错误
+	type Dup struct {
+		A string `xml:"x"`
+		B string `xml:"x"`
+	}
+	var pathErr *xml.TagPathError
+	if _, err := MarshalXML(Dup{}, CONFIDENTIAL_LEVEL0); !errors.As(err, &pathErr) {
+		t.Fatalf("expect tag path error, got %v", err)
+	}
+	type Mismatch struct {
+		In Inner `xml:"other"`
+	}
+	type NsAttr struct {
+		A string `xml:"urn:x a,attr"`
+	}
+	for _, v := range []interface{}{Mismatch{}, NsAttr{}} {
+		if _, err := MarshalXML(v, CONFIDENTIAL_LEVEL0); err == nil {
+			t.Fatalf("%#v: expect error", v)
+		}
+	}
+
+	for _, c := range []struct {
+		v      interface{}
+		expect string
+	}{
+		{nil, ""},
+		{(*Port)(nil), ""},
+		{[]Port{{Proto: "a", Num: 1}, {Num: 2}}, `<Port proto="a">1</Port><Port proto="">2</Port>`},
+		{[]int{1, 2}, "<int>1</int><int>2</int>"},
+	} {
+		if b, err := MarshalXML(c.v, CONFIDENTIAL_LEVEL0); err != nil || string(b) != c.expect {
+			t.Fatalf("%#v: unexpected xml %q, %v", c.v, b, err)
+		}
+	}
+	for _, v := range []interface{}{map[string]int{"a": 1}, struct{}{}, Device{Comment: "a--b"}} {
+		if _, err := MarshalXML(v, CONFIDENTIAL_LEVEL0); err == nil {
+			t.Fatalf("%#v: expect error", v)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	gosifter "github.com/jtuki/gosifter/src"
+	"io"
+	"sync"
+)
+
+// 将筛选之后的 json 写入 io.Writer 的 Encoder（用法与 json.Encoder 一致），如：
+//
+//  enc := api.NewEncoder(w, api.CONFIDENTIAL_LEVEL1)
+//  enc.SetIndent("", "  ")
+//  err := enc.Encode(deviceInfoList)
+//
+// Note:
+//  1. 序列化过程中使用的缓冲区从 sync.Pool 中获取，每次 Encode() 不再分配新的结果 []byte；
+//  2. 与 Marshal() 不同，Encode() 接受任意的值（如结构体的切片），nil 输出为 null；
+//  3. 一个 Encoder 不能在多个 goroutine 中同时使用。
+type Encoder struct {
+	w      io.Writer
+	clevel int
+
+	escapeHTML bool
+	prefix     string
+	indent     string
+}
+
+// 复用的缓冲区
+type encodeBuffer struct {
+	b        []byte
+	indented bytes.Buffer
+}
+
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} { return &encodeBuffer{} },
+}
+
+// 超过此大小的缓冲区不再放回 pool（以免偶尔的大结果长期占用内存）
+const MAX_POOLED_BUFFER_SIZE = 1 << 20
+
+// api function
+//
+// @param
+//  w - 输出的目标
+//  clevel - 最高允许的安全等级（高于此等级的将被筛除）
+func NewEncoder(w io.Writer, clevel int) *Encoder {
+	return &Encoder{w: w, clevel: clevel, escapeHTML: true}
+}
+
+// 输出 v 筛选之后的 json，并以换行符结束
+func (enc *Encoder) Encode(v interface{}) error {
+	eb := encodeBufferPool.Get().(*encodeBuffer)
+	defer func() {
+		if cap(eb.b) <= MAX_POOLED_BUFFER_SIZE && eb.indented.Cap() <= MAX_POOLED_BUFFER_SIZE {
+			encodeBufferPool.Put(eb)
+		}
+	}()
+
+	b, err := enc.marshal(eb.b[:0], v)
+	if err != nil {
+		return err
+	}
+	eb.b = b
+
+	if enc.prefix != "" || enc.indent != "" {
+		eb.indented.Reset()
+		if err := json.Indent(&eb.indented, b, enc.prefix, enc.indent); err != nil {
+			return err
+		}
+		eb.indented.WriteByte('\n')
+		b = eb.indented.Bytes()
+	} else {
+		b = append(b, '\n')
+		eb.b = b
+	}
+
+	_, err = enc.w.Write(b)
+	return err
+}
+
+func (enc *Encoder) marshal(b []byte, v interface{}) ([]byte, error) {
+	// 生成的方法总是转义 HTML 字符
+	if lm, ok := v.(LevelMarshaler); ok && enc.escapeHTML && !isNilPtr(v) {
+		out, err := lm.MarshalJSONLevel(enc.clevel)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, out...), nil
+	}
+	return gosifter.AppendLevel(b, v, enc.clevel, enc.escapeHTML)
+}
+
+// 设置输出的缩进（与 json.Encoder.SetIndent() 一致）；两者均为空时不缩进
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.prefix = prefix
+	enc.indent = indent
+}
+
+// 设置是否转义字符串中的 <、>、&（默认转义，与 json.Encoder.SetEscapeHTML() 一致）
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.escapeHTML = on
+}
@@ -0,0 +1,133 @@
+// gentest 为 gosifter-gen -test 生成的测试提供辅助函数（仅供测试使用，不要在生产代码中引用）。
+package gentest
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jtuki/gosifter/api"
+	gosifter "github.com/jtuki/gosifter/src"
+	"math/rand"
+	"reflect"
+)
+
+// 对比 gosifter-gen 生成的方法与反射路径的输出（供 gosifter-gen -test 生成的测试调用）。
+//
+// @param
+//  v - 实现了 LevelSifter 以及 LevelMarshaler 的结构体指针
+//  rounds - 除了 v 本身之外，再以随机填充的同类型的值对比的次数
+//
+// Note:
+//  在所有的保密级别（包括低于 CONFIDENTIAL_LEVEL0 以及高于 CONFIDENTIAL_LEVEL_MAX 的级别）下分别对比。
+func CheckGenerated(v interface{}, rounds int) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("invalid param type %v", rv.Type())
+	}
+	if err := checkGenerated(v); err != nil {
+		return err
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < rounds; i++ {
+		nv := reflect.New(rv.Elem().Type())
+		fillRandom(nv.Elem(), rnd, 0)
+		if err := checkGenerated(nv.Interface()); err != nil {
+			return fmt.Errorf("round %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+func checkGenerated(v interface{}) error {
+	ls, ok := v.(api.LevelSifter)
+	if !ok {
+		return fmt.Errorf("%T does not implement LevelSifter", v)
+	}
+	lm, ok := v.(api.LevelMarshaler)
+	if !ok {
+		return fmt.Errorf("%T does not implement LevelMarshaler", v)
+	}
+
+	cs, err := gosifter.GetSifter(reflect.TypeOf(v).Elem())
+	if err != nil {
+		return err
+	}
+
+	for level := api.CONFIDENTIAL_LEVEL0 - 1; level <= api.CONFIDENTIAL_LEVEL_MAX+1; level++ {
+		m1, err1 := ls.SiftLevel(level)
+		m2, err2 := cs.SiftStruct(v, level)
+		if (err1 != nil) != (err2 != nil) || !reflect.DeepEqual(m1, m2) {
+			return fmt.Errorf("%T level %d: SiftLevel() = %v, %v; SiftStruct() = %v, %v", v, level, m1, err1, m2, err2)
+		}
+
+		b1, err1 := lm.MarshalJSONLevel(level)
+		b2, err2 := gosifter.MarshalLevel(v, level)
+		if (err1 != nil) != (err2 != nil) || !bytes.Equal(b1, b2) {
+			return fmt.Errorf("%T level %d: MarshalJSONLevel() = %s, %v; MarshalLevel() = %s, %v", v, level, b1, err1, b2, err2)
+		}
+	}
+	return nil
+}
+
+// 随机填充的字符串（包括需要转义的字符）
+var randomStrings = []string{"", "a", "gosifter", "<tag>&", `"quoted"\`, "line\nbreak", "中文", " "}
+
+// 随机填充某一个值（仅限可以设置的域；嵌套深度有限）
+func fillRandom(rv reflect.Value, rnd *rand.Rand, depth int) {
+	if rv.Kind() == reflect.Struct {
+		// 非公开的匿名结构体中公开的域依然可以设置
+		for i := 0; i < rv.NumField(); i++ {
+			fillRandom(rv.Field(i), rnd, depth+1)
+		}
+		return
+	}
+	if !rv.CanSet() || rnd.Intn(4) == 0 {
+		// 保留一部分零值（用于检查 omitempty/omitzero）
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		rv.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(rnd.Int63n(200) - 100)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		rv.SetUint(uint64(rnd.Int63n(200)))
+	case reflect.Float32, reflect.Float64:
+		rv.SetFloat(rnd.NormFloat64() * []float64{1e-9, 1, 1e3, 1e22}[rnd.Intn(4)])
+	case reflect.String:
+		rv.SetString(randomStrings[rnd.Intn(len(randomStrings))])
+	case reflect.Ptr:
+		if depth < 5 {
+			rv.Set(reflect.New(rv.Type().Elem()))
+			fillRandom(rv.Elem(), rnd, depth+1)
+		}
+	case reflect.Slice:
+		if depth < 5 {
+			n := rnd.Intn(3)
+			rv.Set(reflect.MakeSlice(rv.Type(), n, n))
+			for i := 0; i < n; i++ {
+				fillRandom(rv.Index(i), rnd, depth+1)
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			fillRandom(rv.Index(i), rnd, depth+1)
+		}
+	case reflect.Map:
+		if depth < 5 {
+			rv.Set(reflect.MakeMap(rv.Type()))
+			for i := rnd.Intn(3); i > 0; i-- {
+				k := reflect.New(rv.Type().Key()).Elem()
+				e := reflect.New(rv.Type().Elem()).Elem()
+				fillRandom(k, rnd, depth+1)
+				fillRandom(e, rnd, depth+1)
+				rv.SetMapIndex(k, e)
+			}
+		}
+	case reflect.Interface:
+		if rv.NumMethod() == 0 {
+			rv.Set(reflect.ValueOf(randomStrings[rnd.Intn(len(randomStrings))]))
+		}
+	}
+}
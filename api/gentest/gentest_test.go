@@ -0,0 +1,62 @@
+package gentest
+
+import (
+	"fmt"
+	"testing"
+)
+
+// 模拟 gosifter-gen 生成的方法（故意与反射路径的输出不同）
+type generatedStub struct {
+	Name string `json:"name"`
+}
+
+func (g *generatedStub) SiftLevel(clevel int) (map[string]interface{}, error) {
+	return map[string]interface{}{"generated": clevel}, nil
+}
+
+func (g *generatedStub) MarshalJSONLevel(clevel int) ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"generated":%d}`, clevel)), nil
+}
+
+// 与反射路径的输出一致的实现
+type reflectiveStub struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret" confidential:"level2"`
+}
+
+func (r *reflectiveStub) SiftLevel(clevel int) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if clevel >= 0 {
+		m["name"] = r.Name
+	}
+	if clevel >= 2 {
+		m["secret"] = r.Secret
+	}
+	return m, nil
+}
+
+func (r *reflectiveStub) MarshalJSONLevel(clevel int) ([]byte, error) {
+	m, _ := r.SiftLevel(clevel)
+	if _, ok := m["secret"]; ok {
+		return []byte(fmt.Sprintf(`{"name":%q,"secret":%q}`, r.Name, r.Secret)), nil
+	}
+	if _, ok := m["name"]; ok {
+		return []byte(fmt.Sprintf(`{"name":%q}`, r.Name)), nil
+	}
+	return []byte("{}"), nil
+}
+
+func TestCheckGenerated(t *testing.T) {
+	// 与反射路径的输出不一致时报错
+	g := &generatedStub{Name: "stub"}
+	if err := CheckGenerated(g, 10); err == nil {
+		t.Fatal("expect mismatch between generated methods and reflective path")
+	}
+	if err := CheckGenerated(*g, 10); err == nil {
+		t.Fatal("expect error for non-pointer value")
+	}
+
+	if err := CheckGenerated(&reflectiveStub{Name: "a", Secret: "s"}, 0); err != nil {
+		t.Fatal(err)
+	}
+}
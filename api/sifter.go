@@ -0,0 +1,171 @@
+package api
+
+import (
+	"fmt"
+	gosifter "github.com/jtuki/gosifter/src"
+	"reflect"
+	"unsafe"
+)
+
+// 某一个结构体类型的筛选器（类型安全的 api），T 为结构体或者结构体指针，一般在包初始化时获取一次，如：
+//
+//  var deviceSifter = api.MustFor[*DeviceInfo]()
+//
+//  m, err := deviceSifter.Sift(info, api.CONFIDENTIAL_LEVEL1)
+//  b, err := deviceSifter.Marshal(info, api.CONFIDENTIAL_LEVEL1)
+//
+// Note:
+//  1. 类型的校验、sifter 以及 json encoder 的获取在 For() 时完成，之后的调用不再需要 reflect.TypeOf() 以及缓存查找
+//  （Register() 等清空了 encoder 缓存之后自动重新获取）；
+//  2. T 为结构体时按照其指针进行处理（与 SiftStruct(&v) 一致，指针接收者实现的方法同样生效）；
+//  3. T 为结构体指针且传入 nil 时，Sift()/SiftOrdered() 返回 nil，Marshal() 返回 null；
+//  4. 可以在多个 goroutine 中同时使用。
+type Sifter[T any] struct {
+	sifter  *gosifter.Sifter
+	encoder *gosifter.TypeEncoder // 结构体指针的 json encoder
+	isPtr   bool                  // T 是否为结构体指针
+
+	isLevelSifter    bool // *S 是否实现了 LevelSifter（生成的方法）
+	isLevelMarshaler bool // *S 是否实现了 LevelMarshaler（生成的方法）
+}
+
+var (
+	levelSifterType    = reflect.TypeOf((*LevelSifter)(nil)).Elem()
+	levelMarshalerType = reflect.TypeOf((*LevelMarshaler)(nil)).Elem()
+)
+
+// api function
+//
+// 获取 T（结构体或者结构体指针）的筛选器；T 不是结构体（指针）或者标签有误时返回错误。
+func For[T any]() (*Sifter[T], error) {
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	s := &Sifter[T]{}
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+		s.isPtr = true
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid param type %v", rt.Kind())
+	}
+
+	sifter, err := gosifter.GetSifter(rt)
+	if err != nil {
+		return nil, err
+	}
+	s.sifter = sifter
+	s.encoder = gosifter.NewTypeEncoder(reflect.PtrTo(rt))
+	s.isLevelSifter = reflect.PtrTo(rt).Implements(levelSifterType)
+	s.isLevelMarshaler = reflect.PtrTo(rt).Implements(levelMarshalerType)
+	return s, nil
+}
+
+// api function
+//
+// 与 For() 相同，但出错时 panic（用于包级别变量的初始化）。
+func MustFor[T any]() *Sifter[T] {
+	s, err := For[T]()
+	if err != nil {
+		panic(fmt.Sprintf("gosifter: For[%v](): %s", reflect.TypeOf((*T)(nil)).Elem(), err))
+	}
+	return s
+}
+
+// 结构体的指针（T 为结构体指针且 v 为 nil 时返回 nil）
+func (s *Sifter[T]) ptr(v T) interface{} {
+	if !s.isPtr {
+		// 拷贝至新分配的变量（而不是返回 &v），使得 T 为结构体指针时 v 不会逃逸至堆上
+		p := new(T)
+		*p = v
+		return p
+	}
+	// T 为指针类型，直接读取指针的值（无需 reflect.ValueOf()）
+	if *(*unsafe.Pointer)(unsafe.Pointer(&v)) == nil {
+		return nil
+	}
+	return v
+}
+
+// 对应的结构体类型（T 为结构体指针时为其指向的结构体类型）
+func (s *Sifter[T]) Type() reflect.Type {
+	return s.sifter.Type()
+}
+
+// 某一个保密级别下可见的域（即序列化之后的键），按照结构体域的声明顺序排列
+func (s *Sifter[T]) Fields(clevel int) []string {
+	return s.sifter.Fields(clevel)
+}
+
+// 筛选，与 SiftStruct() 一致
+func (s *Sifter[T]) Sift(v T, clevel int) (map[string]interface{}, error) {
+	p := s.ptr(v)
+	if p == nil {
+		return nil, nil
+	}
+	if s.isLevelSifter {
+		return p.(LevelSifter).SiftLevel(clevel)
+	}
+	return s.sifter.SiftStruct(p, clevel)
+}
+
+// 筛选并保持结构体域的声明顺序，与 SiftOrdered() 一致
+func (s *Sifter[T]) SiftOrdered(v T, clevel int) (OrderedMap, error) {
+	p := s.ptr(v)
+	if p == nil {
+		return nil, nil
+	}
+	return s.sifter.SiftOrdered(p, clevel)
+}
+
+// 筛选并深拷贝结果中的引用类型，与 SiftStructCopy() 一致
+func (s *Sifter[T]) SiftStructCopy(v T, clevel int) (map[string]interface{}, error) {
+	p := s.ptr(v)
+	if p == nil {
+		return nil, nil
+	}
+	return s.sifter.SiftStructCopy(p, clevel)
+}
+
+// 返回清零了高于 clevel 的域的深拷贝，与 SiftCopy() 一致
+func (s *Sifter[T]) SiftCopy(v T, clevel int) (T, error) {
+	return SiftCopy(v, clevel)
+}
+
+// 序列化，与 Marshal() 一致
+func (s *Sifter[T]) Marshal(v T, clevel int) ([]byte, error) {
+	p := s.ptr(v)
+	if p == nil {
+		return []byte("null"), nil
+	}
+	if s.isLevelMarshaler {
+		return p.(LevelMarshaler).MarshalJSONLevel(clevel)
+	}
+	return s.encoder.Marshal(p, clevel)
+}
+
+// 按照保密级别输出 MessagePack，与 MarshalMsgpack() 一致
+func (s *Sifter[T]) MarshalMsgpackLevel(v T, clevel int) ([]byte, error) {
+	return gosifter.MarshalMsgpackLevel(s.ptr(v), clevel)
+}
+
+// 按照保密级别输出 YAML，与 MarshalYAML() 一致
+func (s *Sifter[T]) MarshalYAMLLevel(v T, clevel int) ([]byte, error) {
+	return gosifter.MarshalYAMLLevel(s.ptr(v), clevel)
+}
+
+// 按照保密级别输出 XML，与 MarshalXML() 一致
+func (s *Sifter[T]) MarshalXMLLevel(v T, clevel int) ([]byte, error) {
+	return gosifter.MarshalXMLLevel(s.ptr(v), clevel)
+}
+
+// 一次遍历输出所有保密级别下的序列化结果，与 MarshalAllLevels() 一致
+func (s *Sifter[T]) MarshalAllLevels(v T) ([][]byte, error) {
+	p := s.ptr(v)
+	if p == nil {
+		out := make([][]byte, CONFIDENTIAL_LEVEL_MAX+1)
+		for i := range out {
+			out[i] = []byte("null")
+		}
+		return out, nil
+	}
+	return gosifter.MarshalAllLevels(p)
+}
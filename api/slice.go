@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	gosifter "github.com/jtuki/gosifter/src"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// 每个 goroutine 每次领取的元素个数
+const SIFT_SLICE_CHUNK_SIZE = 64
+
+// SiftSlice() 的选项
+type SiftSliceOptions struct {
+	Workers int // 并发的 goroutine 个数；不大于 0 时为 runtime.GOMAXPROCS(0)
+}
+
+// SiftSlice() 中某一个元素筛选失败时返回的错误
+type SliceItemError struct {
+	Index int // 出错的元素的下标
+	Err   error
+}
+
+func (e *SliceItemError) Error() string {
+	return fmt.Sprintf("abort due to item[%d]: %s", e.Index, e.Err)
+}
+
+func (e *SliceItemError) Unwrap() error {
+	return e.Err
+}
+
+// api function
+//
+// 并发地对切片（或者数组）中的每一个结构体进行筛选，结果的顺序与输入的顺序一致。
+//
+// @param
+//  ctx - 取消时尽快停止并返回 ctx.Err()
+//  items - 元素为结构体、结构体指针或者接口的切片/数组（nil 元素的结果为 nil）
+//  clevel - 最高允许的安全等级（高于此等级的将被筛除）
+//  opts - 可以为 nil
+//
+// Note:
+//  1. 各个 goroutine 按照顺序领取 SIFT_SLICE_CHUNK_SIZE 个元素进行处理，出错之后不再领取新的元素，
+//  因此返回的 *SliceItemError 总是下标最小的出错元素（与顺序处理的结果一致）；
+//  2. 切片中的结构体元素按照其指针进行筛选（与 json 标准库序列化切片时一致，元素是可寻址的）。
+func SiftSlice(ctx context.Context, items interface{}, clevel int, opts *SiftSliceOptions) ([]map[string]interface{}, error) {
+	rv := reflect.ValueOf(items)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("invalid param type %v", rv.Kind())
+	}
+	sift, err := itemSifter(rv.Type().Elem(), clevel)
+	if err != nil {
+		return nil, err
+	}
+	// 与元素的个数无关（包括空切片），ctx 已经取消时直接返回
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if opts != nil && opts.Workers > 0 {
+		workers = opts.Workers
+	}
+	n := rv.Len()
+	chunks := (n + SIFT_SLICE_CHUNK_SIZE - 1) / SIFT_SLICE_CHUNK_SIZE
+	if workers > chunks {
+		workers = chunks
+	}
+
+	out := make([]map[string]interface{}, n)
+
+	var (
+		wg        sync.WaitGroup
+		next      int64 = -1 // 最近一次被领取的 chunk
+		stopped   int32      // 是否停止领取（0/1: 否/是）
+		cancelled int32      // 是否因为 ctx 取消而停止（0/1: 否/是）
+	)
+	errs := make([]error, workers) // 各个 goroutine 遇到的第一个错误
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for atomic.LoadInt32(&stopped) == 0 {
+				if ctx.Err() != nil {
+					atomic.StoreInt32(&cancelled, 1)
+					atomic.StoreInt32(&stopped, 1)
+					return
+				}
+				c := int(atomic.AddInt64(&next, 1))
+				if c >= chunks {
+					return
+				}
+				end := (c + 1) * SIFT_SLICE_CHUNK_SIZE
+				if end > n {
+					end = n
+				}
+				for i := c * SIFT_SLICE_CHUNK_SIZE; i < end; i++ {
+					m, err := sift(rv.Index(i))
+					if err != nil {
+						errs[w] = &SliceItemError{Index: i, Err: err}
+						atomic.StoreInt32(&stopped, 1)
+						return
+					}
+					out[i] = m
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var first *SliceItemError
+	for _, err := range errs {
+		if err, ok := err.(*SliceItemError); ok && (first == nil || err.Index < first.Index) {
+			first = err
+		}
+	}
+	if first != nil {
+		return nil, first
+	}
+	if atomic.LoadInt32(&cancelled) == 1 {
+		return nil, ctx.Err()
+	}
+	return out, nil
+}
+
+// 根据元素的类型获取对应的筛选函数
+func itemSifter(et reflect.Type, clevel int) (func(v reflect.Value) (map[string]interface{}, error), error) {
+	switch {
+	case et.Kind() == reflect.Interface:
+		return func(v reflect.Value) (map[string]interface{}, error) {
+			if v.IsNil() {
+				return nil, nil
+			}
+			return SiftStruct(v.Interface(), clevel)
+		}, nil
+	case et.Kind() == reflect.Ptr && et.Elem().Kind() == reflect.Struct:
+		cs, err := gosifter.GetSifter(et.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (map[string]interface{}, error) {
+			if v.IsNil() {
+				return nil, nil
+			}
+			p := v.Interface()
+			if ls, ok := p.(LevelSifter); ok {
+				return ls.SiftLevel(clevel)
+			}
+			return cs.SiftStruct(p, clevel)
+		}, nil
+	case et.Kind() == reflect.Struct:
+		cs, err := gosifter.GetSifter(et)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (map[string]interface{}, error) {
+			var s interface{}
+			if v.CanAddr() {
+				s = v.Addr().Interface()
+			} else {
+				s = v.Interface()
+			}
+			if ls, ok := s.(LevelSifter); ok {
+				return ls.SiftLevel(clevel)
+			}
+			return cs.SiftStruct(s, clevel)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid param type %v", et)
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"io"
+)
+
+// 流式输出的格式
+const (
+	STREAM_FORMAT_JSON_ARRAY = iota // `[{...},{...}]`
+	STREAM_FORMAT_NDJSON            // 每行一个 json（newline-delimited json）
+)
+
+// 默认每输出多少个元素刷新一次（w 实现了 Flush() 时）
+const STREAM_FLUSH_INTERVAL = 100
+
+// 逐个筛选并输出元素的 StreamWriter（用于导出无法一次性放入内存的大量数据），如：
+//
+//  sw := api.NewStreamWriter(w, api.CONFIDENTIAL_LEVEL1, api.STREAM_FORMAT_JSON_ARRAY)
+//  for rows.Next() {
+//  	if err := sw.Write(row); err != nil {...}
+//  }
+//  err := sw.Close()
+//
+// Note:
+//  1. 每个元素筛选之后立即写入 w（与 Encoder 一样复用缓冲区、优先调用生成的方法）；w 实现了
+//  `Flush() error`（如 bufio.Writer）或者 `Flush()`（如 http.Flusher）时按照 SetFlushInterval() 定期刷新；
+//  2. 出错之后 StreamWriter 不再可用，之后的调用（包括 Close()）均返回同一个错误；每个元素先完整地序列化之后
+//  再写入 w，出错的元素本身不会被写入，但是此前的元素（以及 json 数组的 `[`）已经写入 w 且不会再输出 `]`，
+//  即 w 中是一个不完整的 json 数组（ndjson 则是完整的若干行），调用者应当丢弃已经输出的内容（如 http 响应
+//  应当中断连接而不是当作正常结束）；
+//  3. 一个 StreamWriter 不能在多个 goroutine 中同时使用。
+type StreamWriter struct {
+	w      io.Writer
+	enc    *Encoder
+	format int
+
+	count         int // 已经输出的元素个数
+	flushInterval int
+	closed        bool
+	err           error
+}
+
+// api function
+//
+// @param
+//  w - 输出的目标
+//  clevel - 最高允许的安全等级（高于此等级的将被筛除）
+//  format - STREAM_FORMAT_JSON_ARRAY 或者 STREAM_FORMAT_NDJSON
+func NewStreamWriter(w io.Writer, clevel int, format int) *StreamWriter {
+	return &StreamWriter{
+		w:             w,
+		enc:           NewEncoder(w, clevel),
+		format:        format,
+		flushInterval: STREAM_FLUSH_INTERVAL,
+	}
+}
+
+// 设置每输出多少个元素刷新一次；不大于 0 时只在 Close() 时刷新
+func (sw *StreamWriter) SetFlushInterval(n int) {
+	sw.flushInterval = n
+}
+
+// 设置是否转义字符串中的 <、>、&（默认转义）
+func (sw *StreamWriter) SetEscapeHTML(on bool) {
+	sw.enc.SetEscapeHTML(on)
+}
+
+// 筛选并输出一个元素；筛选失败时返回 *SliceItemError（Index 为此元素的序号），此时已经输出的内容不可用
+func (sw *StreamWriter) Write(v interface{}) error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if sw.closed {
+		return io.ErrClosedPipe
+	}
+
+	eb := encodeBufferPool.Get().(*encodeBuffer)
+	defer encodeBufferPool.Put(eb)
+
+	b := eb.b[:0]
+	switch {
+	case sw.format == STREAM_FORMAT_NDJSON:
+	case sw.count == 0:
+		b = append(b, '[')
+	default:
+		b = append(b, ',')
+	}
+
+	b, err := sw.enc.marshal(b, v)
+	if err != nil {
+		sw.err = &SliceItemError{Index: sw.count, Err: err}
+		return sw.err
+	}
+	if sw.format == STREAM_FORMAT_NDJSON {
+		b = append(b, '\n')
+	}
+	if cap(b) <= MAX_POOLED_BUFFER_SIZE {
+		eb.b = b
+	} else {
+		eb.b = nil
+	}
+
+	if _, err := sw.w.Write(b); err != nil {
+		sw.err = err
+		return err
+	}
+	sw.count++
+
+	if sw.flushInterval > 0 && sw.count%sw.flushInterval == 0 {
+		return sw.flush()
+	}
+	return nil
+}
+
+// 输出 channel 中的所有元素，直到 channel 被关闭或者 ctx 被取消（不会调用 Close()）
+func (sw *StreamWriter) WriteChan(ctx context.Context, ch <-chan interface{}) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case v, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := sw.Write(v); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// 输出迭代器中的所有元素（seq 的形式与 iter.Seq[any] 一致，不会调用 Close()）
+func (sw *StreamWriter) WriteSeq(seq func(yield func(interface{}) bool)) error {
+	var err error
+	seq(func(v interface{}) bool {
+		err = sw.Write(v)
+		return err == nil
+	})
+	return err
+}
+
+// 结束输出（json 数组输出 `]`；没有任何元素时输出 `[]`）并刷新；不会关闭 w
+func (sw *StreamWriter) Close() error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if sw.closed {
+		return nil
+	}
+	sw.closed = true
+
+	if sw.format == STREAM_FORMAT_JSON_ARRAY {
+		end := "]\n"
+		if sw.count == 0 {
+			end = "[]\n"
+		}
+		if _, err := io.WriteString(sw.w, end); err != nil {
+			sw.err = err
+			return err
+		}
+	}
+	return sw.flush()
+}
+
+func (sw *StreamWriter) flush() error {
+	switch f := sw.w.(type) {
+	case interface{ Flush() error }:
+		if err := f.Flush(); err != nil {
+			sw.err = err
+			return err
+		}
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	gosifter "github.com/jtuki/gosifter/api"
 	bmi "github.com/jtuki/gosifter/benchmark/internal"
+	gosifter_src "github.com/jtuki/gosifter/src"
 	"runtime"
 	"strconv"
 	"sync"
@@ -22,6 +23,7 @@ const (
 var (
 	gl_deviceInfoList  []*bmi.DeviceInfo
 	gl_runtime_cpu_num int
+	gl_clevel          int // 序列化时采用的保密级别
 )
 
 func prepare_benchmark_data_set() {
@@ -137,8 +139,9 @@ func marshal_routine(routinesNum int, mf marshal_func) {
 	wg.Wait()
 }
 
+// 先筛选出 map[string]interface{}，再进行 json 序列化
 func gosifter_marshal(v interface{}) ([]byte, error) {
-	m, err := gosifter.SiftStruct(v, gosifter.CONFIDENTIAL_LEVEL_MAX)
+	m, err := gosifter.SiftStruct(v, gl_clevel)
 	if err != nil {
 		return nil, err
 	}
@@ -146,14 +149,25 @@ func gosifter_marshal(v interface{}) ([]byte, error) {
 }
 
 func gosifter_marshal_transparent(v interface{}) ([]byte, error) {
-	return gosifter.Marshal(v, gosifter.CONFIDENTIAL_LEVEL_MAX)
+	return gosifter.Marshal(v, gl_clevel)
+}
+
+// 直接使用预编译的 encoder 进行序列化（跳过 api 层的参数检查）
+func gosifter_marshal_stream(v interface{}) ([]byte, error) {
+	return gosifter_src.MarshalLevel(v, gl_clevel)
+}
+
+// 直接调用 gosifter-gen 生成的方法（不经过反射）
+func gosifter_marshal_gen(v interface{}) ([]byte, error) {
+	return v.(*bmi.DeviceInfo).MarshalJSONLevel(gl_clevel)
 }
 
 const DOC_STRING = `====================================================
 Usage:
 
-./bm_tool -type [1|2|3]
-types: json[1], gosifter[2], gosifter_transparent[3]
+./bm_tool -type [1|2|3|4|5] [-level 0-3]
+types: json[1], gosifter[2], gosifter_transparent[3], gosifter_stream[4], gosifter_gen[5]
+level: confidential level used by gosifter (default 3)
 ====================================================
 `
 
@@ -161,7 +175,8 @@ func main() {
 	gl_runtime_cpu_num = runtime.NumCPU()
 	runtime.GOMAXPROCS(gl_runtime_cpu_num)
 
-	marshalType := flag.Int("type", 0, "type of marshaller to use: json[1], gosifter[2], gosifter_transparent[3]")
+	marshalType := flag.Int("type", 0, "type of marshaller to use: json[1], gosifter[2], gosifter_transparent[3], gosifter_stream[4], gosifter_gen[5]")
+	flag.IntVar(&gl_clevel, "level", gosifter.CONFIDENTIAL_LEVEL_MAX, "confidential level used by gosifter")
 	flag.Parse()
 
 	switch *marshalType {
@@ -176,9 +191,15 @@ func main() {
 	case 3:
 		prepare_benchmark_data_set()
 		marshal_routine(gl_runtime_cpu_num, marshal_func(gosifter_marshal_transparent))
+	case 4:
+		prepare_benchmark_data_set()
+		marshal_routine(gl_runtime_cpu_num, marshal_func(gosifter_marshal_stream))
+	case 5:
+		prepare_benchmark_data_set()
+		marshal_routine(gl_runtime_cpu_num, marshal_func(gosifter_marshal_gen))
 	default:
-		fmt.Println("unsupported marshalType: json[1], gosifter[2], gosifter_transparent[3]")
-		fmt.Println(DOC_STRING)
+		fmt.Println("unsupported marshalType: json[1], gosifter[2], gosifter_transparent[3], gosifter_stream[4], gosifter_gen[5]")
+		fmt.Print(DOC_STRING)
 		return
 	}
 }
@@ -0,0 +1,334 @@
+// Code generated by gosifter-gen; DO NOT EDIT.
+
+package internal
+
+import (
+	"strconv"
+
+	gosifter "github.com/jtuki/gosifter/src"
+)
+
+// SiftLevel0 按照保密级别 level0 进行筛选（与 SiftStruct() 的结果一致）
+func (x *DeviceInfo) SiftLevel0() (map[string]interface{}, error) {
+	if x == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, 4)
+	out["domain"] = x.DeviceInfoBasic.Domain
+	out["sub_domain"] = x.DeviceInfoBasic.SubDomain
+	out["physical_device_id"] = x.DeviceInfoBasic.PhysicalDeviceId
+	{
+		m1 := make(map[string]interface{})
+		m1["image_url"] = x.Extended.ImageUrl
+		out["extended"] = m1
+	}
+	return out, nil
+}
+
+// SiftLevel1 按照保密级别 level1 进行筛选（与 SiftStruct() 的结果一致）
+func (x *DeviceInfo) SiftLevel1() (map[string]interface{}, error) {
+	if x == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, 5)
+	out["domain"] = x.DeviceInfoBasic.Domain
+	out["sub_domain"] = x.DeviceInfoBasic.SubDomain
+	out["physical_device_id"] = x.DeviceInfoBasic.PhysicalDeviceId
+	{
+		m1 := make(map[string]interface{})
+		m1["image_url"] = x.Extended.ImageUrl
+		out["extended"] = m1
+	}
+	{
+		m1 := make(map[string]interface{})
+		m1["country"] = x.Meta.Country
+		m1["province"] = x.Meta.Province
+		m1["city"] = x.Meta.City
+		m1["ip"] = x.Meta.IP
+		m1["mod_version"] = x.Meta.ModVersion
+		m1["dev_version"] = x.Meta.DevVersion
+		out["meta"] = m1
+	}
+	return out, nil
+}
+
+// SiftLevel2 按照保密级别 level2 进行筛选（与 SiftStruct() 的结果一致）
+func (x *DeviceInfo) SiftLevel2() (map[string]interface{}, error) {
+	if x == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, 5)
+	out["domain"] = x.DeviceInfoBasic.Domain
+	out["sub_domain"] = x.DeviceInfoBasic.SubDomain
+	out["physical_device_id"] = x.DeviceInfoBasic.PhysicalDeviceId
+	{
+		m1 := make(map[string]interface{})
+		m1["image_url"] = x.Extended.ImageUrl
+		out["extended"] = m1
+	}
+	{
+		m1 := make(map[string]interface{})
+		m1["country"] = x.Meta.Country
+		m1["province"] = x.Meta.Province
+		m1["city"] = x.Meta.City
+		m1["ip"] = x.Meta.IP
+		m1["mod_version"] = x.Meta.ModVersion
+		m1["dev_version"] = x.Meta.DevVersion
+		out["meta"] = m1
+	}
+	return out, nil
+}
+
+// SiftLevel3 按照保密级别 level3 进行筛选（与 SiftStruct() 的结果一致）
+func (x *DeviceInfo) SiftLevel3() (map[string]interface{}, error) {
+	if x == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, 5)
+	out["domain"] = x.DeviceInfoBasic.Domain
+	out["sub_domain"] = x.DeviceInfoBasic.SubDomain
+	out["physical_device_id"] = x.DeviceInfoBasic.PhysicalDeviceId
+	{
+		m1 := make(map[string]interface{})
+		m1["image_url"] = x.Extended.ImageUrl
+		out["extended"] = m1
+	}
+	{
+		m1 := make(map[string]interface{})
+		m1["country"] = x.Meta.Country
+		m1["province"] = x.Meta.Province
+		m1["city"] = x.Meta.City
+		m1["ip"] = x.Meta.IP
+		m1["mod_version"] = x.Meta.ModVersion
+		m1["dev_version"] = x.Meta.DevVersion
+		out["meta"] = m1
+	}
+	return out, nil
+}
+
+// SiftLevel 按照保密级别进行筛选（高于 CONFIDENTIAL_LEVEL_MAX 时按照最高级别处理）
+func (x *DeviceInfo) SiftLevel(clevel int) (map[string]interface{}, error) {
+	switch {
+	case clevel < gosifter.CONFIDENTIAL_LEVEL0:
+		if x == nil {
+			return nil, nil
+		}
+		return map[string]interface{}{}, nil
+	case clevel == gosifter.CONFIDENTIAL_LEVEL0:
+		return x.SiftLevel0()
+	case clevel == gosifter.CONFIDENTIAL_LEVEL1:
+		return x.SiftLevel1()
+	case clevel == gosifter.CONFIDENTIAL_LEVEL2:
+		return x.SiftLevel2()
+	default:
+		return x.SiftLevel3()
+	}
+}
+
+func (x *DeviceInfo) gosifterAppendLevel0(st *gosifter.AppendState, b []byte) ([]byte, error) {
+	n0 := len(b)
+	b = append(b, `,"domain":`...)
+	b = strconv.AppendInt(b, int64(x.DeviceInfoBasic.Domain), 10)
+	b = append(b, `,"sub_domain":`...)
+	b = strconv.AppendInt(b, int64(x.DeviceInfoBasic.SubDomain), 10)
+	b = append(b, `,"physical_device_id":`...)
+	b = st.AppendString(b, string(x.DeviceInfoBasic.PhysicalDeviceId))
+	b = append(b, `,"extended":`...)
+	{
+		n1 := len(b)
+		b = append(b, `,"image_url":`...)
+		b = st.AppendString(b, string(x.Extended.ImageUrl))
+		if len(b) == n1 {
+			b = append(b, '{')
+		} else {
+			b[n1] = '{'
+		}
+		b = append(b, '}')
+	}
+	if len(b) == n0 {
+		b = append(b, '{')
+	} else {
+		b[n0] = '{'
+	}
+	b = append(b, '}')
+	return b, nil
+}
+
+func (x *DeviceInfo) gosifterAppendLevel1(st *gosifter.AppendState, b []byte) ([]byte, error) {
+	n0 := len(b)
+	b = append(b, `,"domain":`...)
+	b = strconv.AppendInt(b, int64(x.DeviceInfoBasic.Domain), 10)
+	b = append(b, `,"sub_domain":`...)
+	b = strconv.AppendInt(b, int64(x.DeviceInfoBasic.SubDomain), 10)
+	b = append(b, `,"physical_device_id":`...)
+	b = st.AppendString(b, string(x.DeviceInfoBasic.PhysicalDeviceId))
+	b = append(b, `,"extended":`...)
+	{
+		n1 := len(b)
+		b = append(b, `,"image_url":`...)
+		b = st.AppendString(b, string(x.Extended.ImageUrl))
+		if len(b) == n1 {
+			b = append(b, '{')
+		} else {
+			b[n1] = '{'
+		}
+		b = append(b, '}')
+	}
+	b = append(b, `,"meta":`...)
+	{
+		n1 := len(b)
+		b = append(b, `,"country":`...)
+		b = st.AppendString(b, string(x.Meta.Country))
+		b = append(b, `,"province":`...)
+		b = st.AppendString(b, string(x.Meta.Province))
+		b = append(b, `,"city":`...)
+		b = st.AppendString(b, string(x.Meta.City))
+		b = append(b, `,"ip":`...)
+		b = st.AppendString(b, string(x.Meta.IP))
+		b = append(b, `,"mod_version":`...)
+		b = st.AppendString(b, string(x.Meta.ModVersion))
+		b = append(b, `,"dev_version":`...)
+		b = st.AppendString(b, string(x.Meta.DevVersion))
+		if len(b) == n1 {
+			b = append(b, '{')
+		} else {
+			b[n1] = '{'
+		}
+		b = append(b, '}')
+	}
+	if len(b) == n0 {
+		b = append(b, '{')
+	} else {
+		b[n0] = '{'
+	}
+	b = append(b, '}')
+	return b, nil
+}
+
+func (x *DeviceInfo) gosifterAppendLevel2(st *gosifter.AppendState, b []byte) ([]byte, error) {
+	n0 := len(b)
+	b = append(b, `,"domain":`...)
+	b = strconv.AppendInt(b, int64(x.DeviceInfoBasic.Domain), 10)
+	b = append(b, `,"sub_domain":`...)
+	b = strconv.AppendInt(b, int64(x.DeviceInfoBasic.SubDomain), 10)
+	b = append(b, `,"physical_device_id":`...)
+	b = st.AppendString(b, string(x.DeviceInfoBasic.PhysicalDeviceId))
+	b = append(b, `,"extended":`...)
+	{
+		n1 := len(b)
+		b = append(b, `,"image_url":`...)
+		b = st.AppendString(b, string(x.Extended.ImageUrl))
+		if len(b) == n1 {
+			b = append(b, '{')
+		} else {
+			b[n1] = '{'
+		}
+		b = append(b, '}')
+	}
+	b = append(b, `,"meta":`...)
+	{
+		n1 := len(b)
+		b = append(b, `,"country":`...)
+		b = st.AppendString(b, string(x.Meta.Country))
+		b = append(b, `,"province":`...)
+		b = st.AppendString(b, string(x.Meta.Province))
+		b = append(b, `,"city":`...)
+		b = st.AppendString(b, string(x.Meta.City))
+		b = append(b, `,"ip":`...)
+		b = st.AppendString(b, string(x.Meta.IP))
+		b = append(b, `,"mod_version":`...)
+		b = st.AppendString(b, string(x.Meta.ModVersion))
+		b = append(b, `,"dev_version":`...)
+		b = st.AppendString(b, string(x.Meta.DevVersion))
+		if len(b) == n1 {
+			b = append(b, '{')
+		} else {
+			b[n1] = '{'
+		}
+		b = append(b, '}')
+	}
+	if len(b) == n0 {
+		b = append(b, '{')
+	} else {
+		b[n0] = '{'
+	}
+	b = append(b, '}')
+	return b, nil
+}
+
+func (x *DeviceInfo) gosifterAppendLevel3(st *gosifter.AppendState, b []byte) ([]byte, error) {
+	n0 := len(b)
+	b = append(b, `,"domain":`...)
+	b = strconv.AppendInt(b, int64(x.DeviceInfoBasic.Domain), 10)
+	b = append(b, `,"sub_domain":`...)
+	b = strconv.AppendInt(b, int64(x.DeviceInfoBasic.SubDomain), 10)
+	b = append(b, `,"physical_device_id":`...)
+	b = st.AppendString(b, string(x.DeviceInfoBasic.PhysicalDeviceId))
+	b = append(b, `,"extended":`...)
+	{
+		n1 := len(b)
+		b = append(b, `,"image_url":`...)
+		b = st.AppendString(b, string(x.Extended.ImageUrl))
+		if len(b) == n1 {
+			b = append(b, '{')
+		} else {
+			b[n1] = '{'
+		}
+		b = append(b, '}')
+	}
+	b = append(b, `,"meta":`...)
+	{
+		n1 := len(b)
+		b = append(b, `,"country":`...)
+		b = st.AppendString(b, string(x.Meta.Country))
+		b = append(b, `,"province":`...)
+		b = st.AppendString(b, string(x.Meta.Province))
+		b = append(b, `,"city":`...)
+		b = st.AppendString(b, string(x.Meta.City))
+		b = append(b, `,"ip":`...)
+		b = st.AppendString(b, string(x.Meta.IP))
+		b = append(b, `,"mod_version":`...)
+		b = st.AppendString(b, string(x.Meta.ModVersion))
+		b = append(b, `,"dev_version":`...)
+		b = st.AppendString(b, string(x.Meta.DevVersion))
+		if len(b) == n1 {
+			b = append(b, '{')
+		} else {
+			b[n1] = '{'
+		}
+		b = append(b, '}')
+	}
+	if len(b) == n0 {
+		b = append(b, '{')
+	} else {
+		b[n0] = '{'
+	}
+	b = append(b, '}')
+	return b, nil
+}
+
+// MarshalJSONLevel 按照保密级别输出 json（与 Marshal() 的结果一致）
+func (x *DeviceInfo) MarshalJSONLevel(clevel int) ([]byte, error) {
+	if x == nil {
+		return []byte("null"), nil
+	}
+	st := gosifter.NewAppendState()
+	defer st.Release()
+	if err := st.Enter(x); err != nil {
+		return nil, err
+	}
+	defer st.Leave(x)
+	b := make([]byte, 0, 256)
+	switch {
+	case clevel < gosifter.CONFIDENTIAL_LEVEL0:
+		return append(b, "{}"...), nil
+	case clevel == gosifter.CONFIDENTIAL_LEVEL0:
+		return x.gosifterAppendLevel0(st, b)
+	case clevel == gosifter.CONFIDENTIAL_LEVEL1:
+		return x.gosifterAppendLevel1(st, b)
+	case clevel == gosifter.CONFIDENTIAL_LEVEL2:
+		return x.gosifterAppendLevel2(st, b)
+	default:
+		return x.gosifterAppendLevel3(st, b)
+	}
+}
@@ -0,0 +1,19 @@
+// Code generated by gosifter-gen; DO NOT EDIT.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/jtuki/gosifter/api/gentest"
+)
+
+func TestGosifterGenDeviceInfo(t *testing.T) {
+	for _, v := range []interface{}{
+		new(DeviceInfo),
+	} {
+		if err := gentest.CheckGenerated(v, 100); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package internal
 
+//go:generate go run github.com/jtuki/gosifter/cmd/gosifter-gen -type DeviceInfo -test
+
 // basic information
 type DeviceInfoBasic struct {
 	Domain           int    `json:"domain"`
@@ -0,0 +1,613 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	gosifter "github.com/jtuki/gosifter/src"
+)
+
+// 结构体中参与序列化的域（嵌入结构体的域已展开），与 src/sifter.go 中的 sifterItem 对应
+type genField struct {
+	path  []*types.Var // 索引路径上的各个域（嵌入结构体需要逐层访问）
+	index []int        // 索引路径（用于处理同名的域以及排序）
+
+	alias    string
+	isTagged bool
+	tag      gosifter.FieldTag
+	isString bool // `string` 选项是否生效
+
+	cLevel int // 保密级别；嵌入结构体的域取索引路径上的最高保密级别
+}
+
+type generator struct {
+	pkg    *types.Package
+	fields map[types.Type][]*genField // 已经解析过的结构体类型
+
+	// 生成的代码中用到的包
+	useStrconv bool
+}
+
+func newGenerator(pkg *types.Package) *generator {
+	return &generator{pkg: pkg, fields: make(map[types.Type][]*genField)}
+}
+
+// 生成所有类型的方法
+func (g *generator) generate(names []string) ([]byte, error) {
+	body := &bytes.Buffer{}
+	for _, name := range names {
+		if err := g.generateType(body, name); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s\n\npackage %s\n\nimport (\n", GENERATED_HEADER, g.pkg.Name())
+	if g.useStrconv {
+		fmt.Fprintf(buf, "\t\"strconv\"\n\n")
+	}
+	fmt.Fprintf(buf, "\tgosifter \"github.com/jtuki/gosifter/src\"\n)\n")
+	buf.Write(body.Bytes())
+	return formatSource(buf)
+}
+
+// 生成对比生成的方法与反射路径的测试
+func (g *generator) generateTest(names []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s\n\npackage %s\n\nimport (\n\t\"testing\"\n\n", GENERATED_HEADER, g.pkg.Name())
+	fmt.Fprintf(buf, "\t\"github.com/jtuki/gosifter/api/gentest\"\n)\n\n")
+	fmt.Fprintf(buf, "func TestGosifterGen%s(t *testing.T) {\n", names[0])
+	fmt.Fprintf(buf, "\tfor _, v := range []interface{}{\n")
+	for _, name := range names {
+		fmt.Fprintf(buf, "\t\tnew(%s),\n", name)
+	}
+	fmt.Fprintf(buf, "\t} {\n\t\tif err := gentest.CheckGenerated(v, 100); err != nil {\n\t\t\tt.Error(err)\n\t\t}\n\t}\n}\n")
+	return formatSource(buf)
+}
+
+func (g *generator) generateType(buf *bytes.Buffer, name string) error {
+	obj := g.pkg.Scope().Lookup(name)
+	if obj == nil {
+		return fmt.Errorf("type %s not found in package %s", name, g.pkg.Name())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if _, isTypeName := obj.(*types.TypeName); !isTypeName || !ok {
+		return fmt.Errorf("%s is not a named type", name)
+	}
+	if named.TypeParams().Len() > 0 {
+		return fmt.Errorf("generic type %s is not supported", name)
+	}
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return fmt.Errorf("%s is not a struct type", name)
+	}
+	if isMarshaler(named) || isMarshaler(types.NewPointer(named)) {
+		return fmt.Errorf("%s implements json.Marshaler or encoding.TextMarshaler", name)
+	}
+
+	fields, err := g.typeFields(named)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	for _, f := range fields {
+		if !g.accessible(f) {
+			return fmt.Errorf("%s: field %s is not accessible from package %s", name, f.selector(), g.pkg.Name())
+		}
+	}
+
+	// 按照固定的保密级别进行筛选
+	for level := gosifter.CONFIDENTIAL_LEVEL0; level <= gosifter.CONFIDENTIAL_LEVEL_MAX; level++ {
+		w := &codeWriter{g: g, level: level}
+		if err := w.siftFields("x", "out", named, 0); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		fmt.Fprintf(buf, "\n// SiftLevel%d 按照保密级别 level%d 进行筛选（与 SiftStruct() 的结果一致）\n", level, level)
+		fmt.Fprintf(buf, "func (x *%s) SiftLevel%d() (map[string]interface{}, error) {\n", name, level)
+		fmt.Fprintf(buf, "if x == nil {\nreturn nil, nil\n}\n")
+		fmt.Fprintf(buf, "out := make(map[string]interface{}, %d)\n", w.count)
+		buf.Write(w.Bytes())
+		fmt.Fprintf(buf, "return out, nil\n}\n")
+	}
+
+	fmt.Fprintf(buf, "\n// SiftLevel 按照保密级别进行筛选（高于 CONFIDENTIAL_LEVEL_MAX 时按照最高级别处理）\n")
+	fmt.Fprintf(buf, "func (x *%s) SiftLevel(clevel int) (map[string]interface{}, error) {\n", name)
+	fmt.Fprintf(buf, "switch {\ncase clevel < gosifter.CONFIDENTIAL_LEVEL0:\nif x == nil {\nreturn nil, nil\n}\nreturn map[string]interface{}{}, nil\n")
+	for level := gosifter.CONFIDENTIAL_LEVEL0; level < gosifter.CONFIDENTIAL_LEVEL_MAX; level++ {
+		fmt.Fprintf(buf, "case clevel == gosifter.CONFIDENTIAL_LEVEL%d:\nreturn x.SiftLevel%d()\n", level, level)
+	}
+	fmt.Fprintf(buf, "default:\nreturn x.SiftLevel%d()\n}\n}\n", gosifter.CONFIDENTIAL_LEVEL_MAX)
+
+	// 按照固定的保密级别直接输出 json
+	for level := gosifter.CONFIDENTIAL_LEVEL0; level <= gosifter.CONFIDENTIAL_LEVEL_MAX; level++ {
+		w := &codeWriter{g: g, level: level}
+		if err := w.appendFields("x", named, 0); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		fmt.Fprintf(buf, "\nfunc (x *%s) gosifterAppendLevel%d(st *gosifter.AppendState, b []byte) ([]byte, error) {\n", name, level)
+		if w.useErr {
+			fmt.Fprintf(buf, "var err error\n")
+		}
+		buf.Write(w.Bytes())
+		fmt.Fprintf(buf, "return b, nil\n}\n")
+	}
+
+	fmt.Fprintf(buf, "\n// MarshalJSONLevel 按照保密级别输出 json（与 Marshal() 的结果一致）\n")
+	fmt.Fprintf(buf, "func (x *%s) MarshalJSONLevel(clevel int) ([]byte, error) {\n", name)
+	fmt.Fprintf(buf, "if x == nil {\nreturn []byte(\"null\"), nil\n}\n")
+	fmt.Fprintf(buf, "st := gosifter.NewAppendState()\ndefer st.Release()\n")
+	fmt.Fprintf(buf, "if err := st.Enter(x); err != nil {\nreturn nil, err\n}\ndefer st.Leave(x)\n")
+	fmt.Fprintf(buf, "b := make([]byte, 0, 256)\nswitch {\ncase clevel < gosifter.CONFIDENTIAL_LEVEL0:\nreturn append(b, \"{}\"...), nil\n")
+	for level := gosifter.CONFIDENTIAL_LEVEL0; level < gosifter.CONFIDENTIAL_LEVEL_MAX; level++ {
+		fmt.Fprintf(buf, "case clevel == gosifter.CONFIDENTIAL_LEVEL%d:\nreturn x.gosifterAppendLevel%d(st, b)\n", level, level)
+	}
+	fmt.Fprintf(buf, "default:\nreturn x.gosifterAppendLevel%d(st, b)\n}\n}\n", gosifter.CONFIDENTIAL_LEVEL_MAX)
+	return nil
+}
+
+// 获取结构体类型中参与序列化的域，规则与 src/sifter.go 中的 generateSifter() 一致
+func (g *generator) typeFields(t types.Type) ([]*genField, error) {
+	if fields, exist := g.fields[t]; exist {
+		return fields, nil
+	}
+
+	// 待展开的匿名结构体（当前层以及下一层）
+	type embeddedStruct struct {
+		typ    types.Type
+		path   []*types.Var
+		index  []int
+		cLevel int
+	}
+	current := []embeddedStruct{}
+	next := []embeddedStruct{{typ: t}}
+
+	// 当前层以及下一层中各个匿名结构体类型出现的次数
+	var count, nextCount map[types.Type]int
+
+	// 已经在较浅层次展开过的结构体类型
+	visited := map[types.Type]bool{}
+
+	fList := make([]*genField, 0)
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[types.Type]int{}
+
+		for _, es := range current {
+			if visited[es.typ] {
+				continue
+			}
+			visited[es.typ] = true
+
+			st := es.typ.Underlying().(*types.Struct)
+			for i := 0; i < st.NumFields(); i++ {
+				sf := st.Field(i)
+
+				// 匿名域指向结构体的指针按照结构体处理
+				ft := types.Unalias(sf.Type())
+				if p, ok := ft.(*types.Pointer); ok {
+					ft = types.Unalias(p.Elem())
+				}
+				_, isStruct := ft.Underlying().(*types.Struct)
+
+				if sf.Anonymous() {
+					if !sf.Exported() && !isStruct {
+						continue
+					}
+				} else if !sf.Exported() {
+					continue
+				}
+
+				tag, err := gosifter.ParseFieldTag(reflect.StructTag(st.Tag(i)))
+				if err != nil {
+					return nil, err
+				}
+				if tag.Ignore {
+					continue
+				}
+				if tag.CLevel < es.cLevel {
+					tag.CLevel = es.cLevel
+				}
+
+				path := append(append([]*types.Var{}, es.path...), sf)
+				index := append(append([]int{}, es.index...), i)
+
+				// 无别名的匿名结构体域：留待下一层展开
+				if tag.Alias == "" && sf.Anonymous() && isStruct {
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, embeddedStruct{typ: ft, path: path, index: index, cLevel: tag.CLevel})
+					}
+					continue
+				}
+
+				f := &genField{
+					path:     path,
+					index:    index,
+					alias:    tag.Alias,
+					isTagged: tag.Alias != "",
+					tag:      tag,
+					isString: tag.AsString && isQuotable(sf.Type()),
+					cLevel:   tag.CLevel,
+				}
+				if f.alias == "" {
+					f.alias = sf.Name()
+				}
+
+				fList = append(fList, f)
+				if count[es.typ] > 1 {
+					// 同一层次中出现了多个相同的匿名结构体类型，再添加一次以确保下面的同名域处理逻辑将其全部忽略
+					fList = append(fList, f)
+				}
+			}
+		}
+	}
+
+	fList = dominantFields(fList)
+	if len(fList) > gosifter.MAX_JSON_FIELD_NUMBER {
+		return nil, fmt.Errorf("abort due to too many json fields (limit %d)", gosifter.MAX_JSON_FIELD_NUMBER)
+	}
+	g.fields[t] = fList
+	return fList, nil
+}
+
+// 处理同名的域，并按照结构体域的声明顺序排列（与 src/sifter.go 中的 dominantSifterItems() 一致）
+func dominantFields(fList []*genField) []*genField {
+	sort.Slice(fList, func(i, j int) bool {
+		x, y := fList[i], fList[j]
+		if x.alias != y.alias {
+			return x.alias < y.alias
+		}
+		if len(x.index) != len(y.index) {
+			return len(x.index) < len(y.index)
+		}
+		if x.isTagged != y.isTagged {
+			return x.isTagged
+		}
+		return indexLess(x.index, y.index)
+	})
+
+	out := fList[:0]
+	for advance, i := 0, 0; i < len(fList); i += advance {
+		f := fList[i]
+		for advance = 1; i+advance < len(fList); advance++ {
+			if fList[i+advance].alias != f.alias {
+				break
+			}
+		}
+		if advance > 1 {
+			fj := fList[i+1]
+			if len(f.index) == len(fj.index) && f.isTagged == fj.isTagged {
+				continue
+			}
+		}
+		out = append(out, f)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return indexLess(out[i].index, out[j].index)
+	})
+	return out
+}
+
+func indexLess(x, y []int) bool {
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return len(x) < len(y)
+}
+
+// 域的访问路径（如 `.Base.ID`）
+func (f *genField) selector() string {
+	names := make([]string, len(f.path))
+	for i, v := range f.path {
+		names[i] = v.Name()
+	}
+	return "." + strings.Join(names, ".")
+}
+
+// 域的类型
+func (f *genField) typ() types.Type {
+	return f.path[len(f.path)-1].Type()
+}
+
+// 生成的代码能否访问此域（其他包中非公开的域无法访问）
+func (g *generator) accessible(f *genField) bool {
+	for _, v := range f.path {
+		if !v.Exported() && v.Pkg() != g.pkg {
+			return false
+		}
+	}
+	return true
+}
+
+// 结构体类型的值能否直接展开至生成的代码中（而不是交由反射路径处理）
+func (g *generator) inlinable(t types.Type) bool {
+	t = types.Unalias(t)
+	if _, ok := t.Underlying().(*types.Struct); !ok {
+		return false
+	}
+	if isMarshaler(t) || isMarshaler(types.NewPointer(t)) {
+		return false
+	}
+	fields, err := g.typeFields(t)
+	if err != nil {
+		// 交由反射路径报告错误
+		return false
+	}
+	for _, f := range fields {
+		if !g.accessible(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// 生成某一个保密级别下的代码
+type codeWriter struct {
+	bytes.Buffer
+	g     *generator
+	level int
+
+	count  int  // 最外层输出的域的个数
+	useErr bool // 是否用到了 err 变量
+}
+
+// 生成筛选结构体的代码，结果写入 out（map[string]interface{}）
+func (w *codeWriter) siftFields(recv, out string, t types.Type, depth int) error {
+	fields, err := w.g.typeFields(t)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		if f.cLevel > w.level {
+			continue
+		}
+		if depth == 0 {
+			w.count++
+		}
+		expr := recv + f.selector()
+		conds, include := fieldConds(recv, f)
+		if !include {
+			continue
+		}
+		if len(conds) > 0 {
+			fmt.Fprintf(w, "if %s {\n", strings.Join(conds, " && "))
+		}
+
+		ft := f.typ()
+		switch {
+		case f.isString:
+			fmt.Fprintf(w, "if v, err := gosifter.QuoteField(&%s); err != nil {\nreturn nil, err\n} else {\n%s[%q] = v\n}\n", expr, out, f.alias)
+		case leafKind(ft) != "":
+			fmt.Fprintf(w, "%s[%q] = %s\n", out, f.alias, expr)
+		case w.g.inlinable(ft):
+			m := fmt.Sprintf("m%d", depth+1)
+			fmt.Fprintf(w, "{\n%s := make(map[string]interface{})\n", m)
+			if err := w.siftFields(expr, m, ft, depth+1); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s[%q] = %s\n}\n", out, f.alias, m)
+		default:
+			fmt.Fprintf(w, "if v, err := gosifter.SiftField(&%s, %d); err != nil {\nreturn nil, err\n} else {\n%s[%q] = v\n}\n", expr, w.level, out, f.alias)
+		}
+
+		if len(conds) > 0 {
+			fmt.Fprintf(w, "}\n")
+		}
+	}
+	return nil
+}
+
+// 生成输出结构体 json 的代码（追加至 b）
+//
+// Note:
+//  每一个域都以 `,"alias":` 开始输出，结束时再将第一个逗号替换为 `{`（没有输出任何域时直接输出 `{}`）。
+func (w *codeWriter) appendFields(recv string, t types.Type, depth int) error {
+	fields, err := w.g.typeFields(t)
+	if err != nil {
+		return err
+	}
+
+	n := fmt.Sprintf("n%d", depth)
+	fmt.Fprintf(w, "%s := len(b)\n", n)
+	for _, f := range fields {
+		if f.cLevel > w.level {
+			continue
+		}
+		expr := recv + f.selector()
+		conds, include := fieldConds(recv, f)
+		if !include {
+			continue
+		}
+		if len(conds) > 0 {
+			fmt.Fprintf(w, "if %s {\n", strings.Join(conds, " && "))
+		}
+
+		name := gosifter.AppendString([]byte{','}, f.alias)
+		fmt.Fprintf(w, "b = append(b, `%s:`...)\n", name)
+
+		ft := f.typ()
+		switch kind := leafKind(ft); {
+		case f.isString:
+			w.useErr = true
+			fmt.Fprintf(w, "if b, err = st.AppendQuotedField(b, &%s); err != nil {\nreturn nil, err\n}\n", expr)
+		case kind == "bool":
+			w.g.useStrconv = true
+			fmt.Fprintf(w, "b = strconv.AppendBool(b, bool(%s))\n", expr)
+		case kind == "int":
+			w.g.useStrconv = true
+			fmt.Fprintf(w, "b = strconv.AppendInt(b, int64(%s), 10)\n", expr)
+		case kind == "uint":
+			w.g.useStrconv = true
+			fmt.Fprintf(w, "b = strconv.AppendUint(b, uint64(%s), 10)\n", expr)
+		case kind == "float32" || kind == "float64":
+			w.useErr = true
+			fmt.Fprintf(w, "if b, err = st.AppendFloat(b, float64(%s), %s); err != nil {\nreturn nil, err\n}\n", expr, strings.TrimPrefix(kind, "float"))
+		case kind == "string":
+			fmt.Fprintf(w, "b = st.AppendString(b, string(%s))\n", expr)
+		case w.g.inlinable(ft):
+			fmt.Fprintf(w, "{\n")
+			if err := w.appendFields(expr, ft, depth+1); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "}\n")
+		default:
+			w.useErr = true
+			fmt.Fprintf(w, "if b, err = st.AppendField(b, &%s, %d); err != nil {\nreturn nil, err\n}\n", expr, w.level)
+		}
+
+		if len(conds) > 0 {
+			fmt.Fprintf(w, "}\n")
+		}
+	}
+	fmt.Fprintf(w, "if len(b) == %s {\nb = append(b, '{')\n} else {\nb[%s] = '{'\n}\nb = append(b, '}')\n", n, n)
+	return nil
+}
+
+// 输出某一个域的条件（路径上的嵌入指针不为 nil，以及 omitempty/omitzero 选项）；include 为 false 表示此域总是被忽略
+func fieldConds(recv string, f *genField) (conds []string, include bool) {
+	expr := recv
+	for _, v := range f.path[:len(f.path)-1] {
+		expr += "." + v.Name()
+		if _, ok := types.Unalias(v.Type()).(*types.Pointer); ok {
+			conds = append(conds, expr+" != nil")
+		}
+	}
+	expr = recv + f.selector()
+	ft := f.typ()
+
+	if f.tag.OmitEmpty {
+		switch u := ft.Underlying().(type) {
+		case *types.Basic:
+			switch info := u.Info(); {
+			case info&types.IsBoolean != 0:
+				conds = append(conds, expr)
+			case info&(types.IsInteger|types.IsFloat) != 0:
+				conds = append(conds, expr+" != 0")
+			case info&types.IsString != 0:
+				conds = append(conds, expr+` != ""`)
+			}
+		case *types.Slice, *types.Map:
+			conds = append(conds, "len("+expr+") != 0")
+		case *types.Array:
+			if u.Len() == 0 {
+				return nil, false
+			}
+		case *types.Pointer, *types.Interface:
+			conds = append(conds, expr+" != nil")
+		}
+	}
+
+	if f.tag.OmitZero {
+		switch u := ft.Underlying().(type) {
+		case *types.Pointer, *types.Interface:
+			if hasIsZero(ft) {
+				conds = append(conds, "!gosifter.IsZeroField(&"+expr+")")
+			} else {
+				conds = append(conds, expr+" != nil")
+			}
+		default:
+			if hasIsZero(ft) || hasIsZero(types.NewPointer(ft)) {
+				conds = append(conds, "!"+expr+".IsZero()")
+				break
+			}
+			switch u := u.(type) {
+			case *types.Basic:
+				switch info := u.Info(); {
+				case info&types.IsBoolean != 0:
+					conds = append(conds, expr)
+				case info&types.IsInteger != 0:
+					conds = append(conds, expr+" != 0")
+				case info&types.IsString != 0:
+					conds = append(conds, expr+` != ""`)
+				default:
+					// 浮点数的 -0 不是零值，交由反射路径判断
+					conds = append(conds, "!gosifter.IsZeroField(&"+expr+")")
+				}
+			case *types.Slice, *types.Map, *types.Chan, *types.Signature:
+				conds = append(conds, expr+" != nil")
+			default:
+				conds = append(conds, "!gosifter.IsZeroField(&"+expr+")")
+			}
+		}
+	}
+	return conds, true
+}
+
+// 可以直接输出的基本类型（布尔值、整数、浮点数、字符串）；其他类型返回空字符串
+func leafKind(t types.Type) string {
+	t = types.Unalias(t)
+	if isMarshaler(t) || isMarshaler(types.NewPointer(t)) || isJsonNumber(t) {
+		return ""
+	}
+	u, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch info := u.Info(); {
+	case info&types.IsBoolean != 0:
+		return "bool"
+	case info&types.IsInteger != 0 && info&types.IsUnsigned != 0:
+		return "uint"
+	case info&types.IsInteger != 0:
+		return "int"
+	case u.Kind() == types.Float32:
+		return "float32"
+	case u.Kind() == types.Float64:
+		return "float64"
+	case info&types.IsString != 0:
+		return "string"
+	}
+	return ""
+}
+
+// `string` 选项是否对此类型生效（与 src/sifter.go 中的 isQuotableType() 一致）
+func isQuotable(t types.Type) bool {
+	t = types.Unalias(t)
+	if p, ok := t.(*types.Pointer); ok {
+		t = types.Unalias(p.Elem())
+	}
+	if isMarshaler(t) || isMarshaler(types.NewPointer(t)) {
+		return false
+	}
+	u, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	return u.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0 && u.Info()&types.IsComplex == 0
+}
+
+func isJsonNumber(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "encoding/json" && obj.Name() == "Number"
+}
+
+// 类型是否实现了 json.Marshaler 或者 encoding.TextMarshaler
+func isMarshaler(t types.Type) bool {
+	return hasMethod(t, "MarshalJSON", 0, 2) || hasMethod(t, "MarshalText", 0, 2)
+}
+
+// 类型是否实现了 `IsZero() bool`
+func hasIsZero(t types.Type) bool {
+	return hasMethod(t, "IsZero", 0, 1)
+}
+
+// 类型的方法集中是否存在指定参数个数以及返回值个数的方法
+func hasMethod(t types.Type, name string, params, results int) bool {
+	sel := types.NewMethodSet(t).Lookup(nil, name)
+	if sel == nil {
+		return false
+	}
+	sig, ok := sel.Type().(*types.Signature)
+	return ok && sig.Params().Len() == params && sig.Results().Len() == results
+}
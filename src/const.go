@@ -2,6 +2,9 @@ package api
 
 const (
 	MAX_JSON_FIELD_NUMBER = 4096
+
+	MAX_SIFT_DEPTH          = 10000 // 筛选时允许的最大嵌套深度
+	SIFT_CYCLE_DETECT_DEPTH = 1000  // 嵌套深度超过此值之后开始检测循环引用
 )
 
 const (
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// 深拷贝筛选结果中直接输出的原值（切片、映射、指针等），使得筛选结果与原对象之间不再共享可变的内存。
+//
+// Note:
+//  1. 结构体的非公开域与公开的域一样（通过其地址）深拷贝以及筛选；
+//  2. chan、func、unsafe.Pointer 以及 sharedTypes 中的类型不做拷贝；
+//  3. 原值中指向同一处的指针/切片/映射在拷贝之后依然指向同一处（因此循环引用也能正确拷贝）；
+//  4. 映射的键同样被拷贝以及筛选（键中的指针指向其拷贝）；筛选之后不同的键变为相同时返回错误。
+type deepCopier struct {
+	seen map[deepCopyKey]reflect.Value
+
+	sift  bool  // 是否同时清零保密级别高于 level 的结构体域（参考 SiftValue()）
+	level int   // 最高允许的保密级别
+	err   error // 拷贝过程中遇到的第一个错误
+}
+
+// 各个结构体类型的域（按照 reflect.Type.Field(i) 的顺序）的保密级别
+var fieldLevelCache sync.Map // map[reflect.Type][]int
+
+// 不做拷贝的类型（不可变、并且以指针本身作为标识，拷贝之后会改变其语义，如 time.Local）
+var sharedTypes = map[reflect.Type]bool{
+	reflect.TypeOf((*time.Location)(nil)): true,
+	reflect.TypeOf(reflect.Value{}):       true,
+}
+
+// reflect.Type 的实现（运行时的类型信息）同样不做拷贝
+var reflectTypeType = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+
+// api function
+//
+// 深拷贝 v（必须可以调用 Interface()），并将其中保密级别高于 maxConfidentialLevel 的结构体域清零（递归地处理嵌套的
+// 结构体、指针、切片、映射以及接口的动态值），返回与 v 类型一致的值。
+//
+// Note:
+//  1. 非公开的域与公开的域一样深拷贝以及筛选（保密级别高于 maxConfidentialLevel 时清零）；
+//  2. 域的保密级别只取决于其 confidential 标签，与 json 标签无关（`json:"-"` 的域同样按照其保密级别处理）；
+//  3. 实现了 json.Marshaler 等方法的类型同样按照其域进行拷贝；
+//  4. 映射的键同样被筛选，筛选之后不同的键变为相同时返回错误。
+func SiftValue(v reflect.Value, maxConfidentialLevel int) (reflect.Value, error) {
+	c := &deepCopier{sift: true, level: maxConfidentialLevel}
+	out := c.copy(v)
+	if c.err != nil {
+		return reflect.Value{}, c.err
+	}
+	return out, nil
+}
+
+// 获取结构体各个域的保密级别
+func fieldLevels(t reflect.Type) ([]int, error) {
+	if levels, ok := fieldLevelCache.Load(t); ok {
+		return levels.([]int), nil
+	}
+	levels := make([]int, t.NumField())
+	for i := range levels {
+		clevel, err := parseConfidentialTags(t.Field(i).Tag.Get(TAG_CONFIDENTIAL))
+		if err != nil {
+			return nil, err
+		}
+		levels[i] = clevel
+	}
+	fieldLevelCache.Store(t, levels)
+	return levels, nil
+}
+
+type deepCopyKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// 拷贝 rv 并以 interface{} 返回（类型与 rv 一致）
+func deepCopy(rv reflect.Value) interface{} {
+	c := &deepCopier{}
+	return c.copy(rv).Interface()
+}
+
+// 拷贝 rv 并返回其指针（用于指针接收者实现的序列化方法）
+func deepCopyAddr(rv reflect.Value) interface{} {
+	c := &deepCopier{}
+	p := reflect.New(rv.Type())
+	p.Elem().Set(c.copy(rv))
+	return p.Interface()
+}
+
+func (c *deepCopier) copy(rv reflect.Value) reflect.Value {
+	t := rv.Type()
+	if sharedTypes[t] || (t.Kind() == reflect.Ptr && t.Implements(reflectTypeType)) {
+		return rv
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return reflect.Zero(t)
+		}
+		key := deepCopyKey{typ: t, ptr: rv.Pointer()}
+		if v, ok := c.seen[key]; ok {
+			return v
+		}
+		out := reflect.New(t.Elem())
+		c.remember(key, out)
+		out.Elem().Set(c.copy(rv.Elem()))
+		return out
+	case reflect.Slice:
+		if rv.IsNil() {
+			return reflect.Zero(t)
+		}
+		key := deepCopyKey{typ: t, ptr: rv.Pointer(), len: rv.Len()}
+		if v, ok := c.seen[key]; ok {
+			return v
+		}
+		out := reflect.MakeSlice(t, rv.Len(), rv.Len())
+		c.remember(key, out)
+		if isFlatType(t.Elem()) {
+			reflect.Copy(out, rv)
+		} else {
+			for i := 0; i < rv.Len(); i++ {
+				out.Index(i).Set(c.copy(rv.Index(i)))
+			}
+		}
+		return out
+	case reflect.Map:
+		if rv.IsNil() {
+			return reflect.Zero(t)
+		}
+		key := deepCopyKey{typ: t, ptr: rv.Pointer()}
+		if v, ok := c.seen[key]; ok {
+			return v
+		}
+		out := reflect.MakeMapWithSize(t, rv.Len())
+		c.remember(key, out)
+		flatKey := isFlatType(t.Key())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := iter.Key()
+			if !flatKey {
+				if k = c.copy(k); out.MapIndex(k).IsValid() {
+					// 不输出键本身（其中可能包含被筛除的内容）
+					c.fail(fmt.Errorf("abort due to map keys of %v colliding after sift", t))
+					return reflect.Zero(t)
+				}
+			}
+			out.SetMapIndex(k, c.copy(iter.Value()))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(t).Elem()
+		out.Set(rv)
+		if !isFlatType(t.Elem()) {
+			for i := 0; i < rv.Len(); i++ {
+				out.Index(i).Set(c.copy(rv.Index(i)))
+			}
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(t).Elem()
+		out.Set(rv)
+		c.copyFields(out)
+		return out
+	case reflect.Interface:
+		if rv.IsNil() {
+			return reflect.Zero(t)
+		}
+		out := reflect.New(t).Elem()
+		out.Set(c.copy(rv.Elem()))
+		return out
+	default:
+		return rv
+	}
+}
+
+// 拷贝结构体的域（out 已经是原值的浅拷贝，并且可寻址）
+func (c *deepCopier) copyFields(out reflect.Value) {
+	t := out.Type()
+
+	var levels []int
+	if c.sift {
+		var err error
+		if levels, err = fieldLevels(t); err != nil {
+			c.fail(err)
+			return
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := out.Field(i)
+		hidden := c.sift && levels[i] > c.level
+		if !hidden && isFlatType(f.Type()) {
+			continue
+		}
+		if !f.CanSet() {
+			// 非公开的域通过其地址读取以及赋值
+			f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+		}
+
+		if hidden {
+			f.Set(reflect.Zero(f.Type()))
+		} else {
+			f.Set(c.copy(f))
+		}
+	}
+}
+
+// 记录拷贝过程中遇到的第一个错误
+func (c *deepCopier) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *deepCopier) remember(key deepCopyKey, v reflect.Value) {
+	if c.seen == nil {
+		c.seen = make(map[deepCopyKey]reflect.Value)
+	}
+	c.seen[key] = v
+}
+
+// 类型的值是否不包含任何引用（赋值即完成拷贝）
+func isFlatType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.String:
+		return true
+	case reflect.Array:
+		return isFlatType(t.Elem())
+	}
+	return false
+}
@@ -0,0 +1,777 @@
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// 直接按照保密级别输出 json 序列化结果（不经过中间的 map[string]interface{}）。
+//
+// 对于每一个（类型，保密级别）组合，预先编译对应的 encoder：结构体中高于保密级别的域在编译时即被剔除，
+// 输出时按照结构体域的声明顺序直接写入 json 字节流。输出结果中的键值与先 SiftStruct() 再 json.Marshal() 的结果
+// 一致，键的顺序与对结构体直接调用 json.Marshal() 一致（即结构体域的声明顺序，而不是 map 的字典序）。
+//
+// @refer `/encoding/json/encode.go`
+
+type encodeState struct {
+	bytes.Buffer
+	siftState
+
+	escapeHTML bool     // 是否转义字符串中的 <、>、&（与 json 标准库的默认行为一致）
+	scratch    [64]byte // 用于数值的格式化
+}
+
+type encoderFunc func(e *encodeState, v reflect.Value) error
+
+type encoderKey struct {
+	typ   reflect.Type
+	level int
+}
+
+var encoderCache sync.Map // map[encoderKey]encoderFunc
+
+// encoder 缓存被清空的次数（用于使 TypeEncoder 预先获取的 encoder 失效）
+var encoderCacheGen uint32
+
+var numberType = reflect.TypeOf(json.Number(""))
+
+// 按照保密级别对任意值进行 json 序列化。
+//
+// @param
+//  v - 需要执行筛选/脱敏的值（结构体、指针、切片、映射等均可）
+//  maxConfidentialLevel - 最高允许的安全等级（高于此等级的将被筛除）
+func MarshalLevel(v interface{}, maxConfidentialLevel int) ([]byte, error) {
+	return AppendLevel(nil, v, maxConfidentialLevel, true)
+}
+
+// 按照保密级别对任意值进行 json 序列化，并将结果追加至 b（用于复用调用者的缓冲区）。
+//
+// @param
+//  escapeHTML - 是否转义字符串中的 <、>、&（json 标准库的默认行为）
+func AppendLevel(b []byte, v interface{}, maxConfidentialLevel int, escapeHTML bool) ([]byte, error) {
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	e.escapeHTML = escapeHTML
+	if err := e.marshal(v, maxConfidentialLevel); err != nil {
+		return nil, err
+	}
+	return append(b, e.Bytes()...), nil
+}
+
+// 复用的 encodeState（及其缓冲区）
+var encodeStatePool sync.Pool
+
+func newEncodeState() *encodeState {
+	if v := encodeStatePool.Get(); v != nil {
+		e := v.(*encodeState)
+		e.Reset()
+		return e
+	}
+	return &encodeState{}
+}
+
+func (e *encodeState) marshal(v interface{}, maxConfidentialLevel int) error {
+	if v == nil {
+		e.WriteString("null")
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	return typeEncoder(rv.Type(), maxConfidentialLevel)(e, rv)
+}
+
+// 获取（或者编译）某一个类型在某一个保密级别下的 encoder。
+//
+// Note:
+//  1. 递归类型在编译过程中会再次遇到自身，此时先放入一个等待编译完成的间接 encoder（与 json 标准库的做法一致）；
+//  2. 超出范围的保密级别与边界上的级别共用 encoder（参考 cachedSifter.visibleItems()），以免缓存无限增长。
+func typeEncoder(t reflect.Type, level int) encoderFunc {
+	if level > CONFIDENTIAL_LEVEL_MAX {
+		level = CONFIDENTIAL_LEVEL_MAX
+	} else if level < CONFIDENTIAL_LEVEL0 {
+		level = CONFIDENTIAL_LEVEL0 - 1
+	}
+	key := encoderKey{typ: t, level: level}
+
+	if f, ok := encoderCache.Load(key); ok {
+		return f.(encoderFunc)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		real encoderFunc
+	)
+	wg.Add(1)
+	f, loaded := encoderCache.LoadOrStore(key, encoderFunc(func(e *encodeState, v reflect.Value) error {
+		wg.Wait()
+		return real(e, v)
+	}))
+	if loaded {
+		return f.(encoderFunc)
+	}
+
+	real = newTypeEncoder(t, level, true)
+	wg.Done()
+	encoderCache.Store(key, real)
+	return real
+}
+
+// 清空 encoder 缓存，包括 MarshalAllLevels() 使用的缓存（已经取得的 encoder 依然可以继续使用）
+func resetEncoderCache() {
+	for _, cache := range []*sync.Map{&encoderCache, &multiEncoderCache, &levelDependentCache} {
+		cache.Range(func(key, _ interface{}) bool {
+			cache.Delete(key)
+			return true
+		})
+	}
+	// 在清空之后递增：期间获取的 encoder 依然被视为过期
+	atomic.AddUint32(&encoderCacheGen, 1)
+}
+
+// 某一个类型在各个保密级别下的 encoder，供类型固定的调用者（如 api.Sifter[T]）预先获取，之后的调用不再查找缓存。
+//
+// Note:
+//  encoder 缓存被清空（Register()、SetRejectUnregistered()）之后，下一次调用时重新获取。
+type TypeEncoder struct {
+	typ      reflect.Type
+	encoders atomic.Value // *typeEncoders
+}
+
+type typeEncoders struct {
+	gen   uint32
+	funcs [CONFIDENTIAL_LEVEL_MAX - CONFIDENTIAL_LEVEL0 + 2]encoderFunc // 下标为 level - (CONFIDENTIAL_LEVEL0 - 1)
+}
+
+// 获取类型 t 在各个保密级别下的 encoder
+func NewTypeEncoder(t reflect.Type) *TypeEncoder {
+	te := &TypeEncoder{typ: t}
+	te.load()
+	return te
+}
+
+func (te *TypeEncoder) load() *typeEncoders {
+	gen := atomic.LoadUint32(&encoderCacheGen)
+	if encs, _ := te.encoders.Load().(*typeEncoders); encs != nil && encs.gen == gen {
+		return encs
+	}
+	encs := &typeEncoders{gen: gen}
+	for i := range encs.funcs {
+		encs.funcs[i] = typeEncoder(te.typ, CONFIDENTIAL_LEVEL0-1+i)
+	}
+	te.encoders.Store(encs)
+	return encs
+}
+
+// 按照保密级别进行 json 序列化，与 MarshalLevel() 一致（v 的类型必须为 NewTypeEncoder() 时的类型）
+func (te *TypeEncoder) Marshal(v interface{}, maxConfidentialLevel int) ([]byte, error) {
+	level := maxConfidentialLevel
+	if level > CONFIDENTIAL_LEVEL_MAX {
+		level = CONFIDENTIAL_LEVEL_MAX
+	} else if level < CONFIDENTIAL_LEVEL0 {
+		level = CONFIDENTIAL_LEVEL0 - 1
+	}
+	f := te.load().funcs[level-(CONFIDENTIAL_LEVEL0-1)]
+
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	e.escapeHTML = true
+	if err := f(e, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), e.Bytes()...), nil
+}
+
+// @param
+//  allowAddr - 是否允许在值可寻址时使用指针接收者实现的序列化方法
+func newTypeEncoder(t reflect.Type, level int, allowAddr bool) encoderFunc {
+	if t.Kind() != reflect.Ptr && allowAddr && reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return newCondAddrEncoder(addrMarshalerEncoder, newTypeEncoder(t, level, false))
+	}
+	if t.Implements(jsonMarshalerType) {
+		return marshalerEncoder
+	}
+	if t.Kind() != reflect.Ptr && allowAddr && reflect.PtrTo(t).Implements(textMarshalerType) {
+		return newCondAddrEncoder(addrTextMarshalerEncoder, newTypeEncoder(t, level, false))
+	}
+	if t.Implements(textMarshalerType) {
+		return textMarshalerEncoder
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolEncoder
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intEncoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintEncoder
+	case reflect.Float32:
+		return float32Encoder
+	case reflect.Float64:
+		return float64Encoder
+	case reflect.String:
+		return stringEncoder
+	case reflect.Interface:
+		return newInterfaceEncoder(level)
+	case reflect.Struct:
+		return newStructEncoder(t, level)
+	case reflect.Map:
+		return newMapEncoder(t, level)
+	case reflect.Slice:
+		return newSliceEncoder(t, level)
+	case reflect.Array:
+		return newArrayEncoder(t, level)
+	case reflect.Ptr:
+		return newPtrEncoder(t, level)
+	default:
+		return newErrorEncoder(fmt.Errorf("json: unsupported type: %v", t))
+	}
+}
+
+func newErrorEncoder(err error) encoderFunc {
+	return func(e *encodeState, v reflect.Value) error {
+		return err
+	}
+}
+
+func boolEncoder(e *encodeState, v reflect.Value) error {
+	e.Write(strconv.AppendBool(e.scratch[:0], v.Bool()))
+	return nil
+}
+
+func intEncoder(e *encodeState, v reflect.Value) error {
+	e.Write(strconv.AppendInt(e.scratch[:0], v.Int(), 10))
+	return nil
+}
+
+func uintEncoder(e *encodeState, v reflect.Value) error {
+	e.Write(strconv.AppendUint(e.scratch[:0], v.Uint(), 10))
+	return nil
+}
+
+func float32Encoder(e *encodeState, v reflect.Value) error {
+	return e.float(v.Float(), 32)
+}
+
+func float64Encoder(e *encodeState, v reflect.Value) error {
+	return e.float(v.Float(), 64)
+}
+
+// 浮点数的格式与 json 标准库保持一致（ES6 的数值格式）
+func (e *encodeState) float(f float64, bits int) error {
+	b, err := appendFloat(e.scratch[:0], f, bits)
+	if err != nil {
+		return err
+	}
+	e.Write(b)
+	return nil
+}
+
+// 将浮点数的 json 格式追加至 b
+func appendFloat(b []byte, f float64, bits int) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("json: unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits))
+	}
+
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	n0 := len(b)
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n-n0 >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
+
+func stringEncoder(e *encodeState, v reflect.Value) error {
+	if v.Type() == numberType {
+		return e.number(v.String())
+	}
+	e.string(v.String(), e.escapeHTML)
+	return nil
+}
+
+func (e *encodeState) number(numStr string) error {
+	// In Go1.5 the empty string encodes to "0", while this is not a valid number literal
+	// we keep compatibility so check validity after this.
+	if numStr == "" {
+		numStr = "0"
+	}
+	if !isValidNumber(numStr) {
+		return fmt.Errorf("json: invalid number literal %q", numStr)
+	}
+	e.WriteString(numStr)
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// 输出 json 字符串（包括转义）
+func (e *encodeState) string(s string, escapeHTML bool) {
+	e.Write(appendString(e.AvailableBuffer(), s, escapeHTML))
+}
+
+// 将 json 字符串（包括转义）追加至 b
+func appendString(b []byte, s string, escapeHTML bool) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && (!escapeHTML || (c != '<' && c != '>' && c != '&')) {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '\\', '"':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, `\b`...)
+			case '\f':
+				b = append(b, `\f`...)
+			case '\n':
+				b = append(b, `\n`...)
+			case '\r':
+				b = append(b, `\r`...)
+			case '\t':
+				b = append(b, `\t`...)
+			default:
+				// This encodes bytes < 0x20 except for \b, \f, \n, \r and \t.
+				// If escapeHTML is set, it also escapes <, >, and &
+				// because they can lead to security holes when
+				// user-controlled strings are rendered into JSON
+				// and served to some browsers.
+				b = append(b, `\u00`...)
+				b = append(b, hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 is LINE SEPARATOR.
+		// U+2029 is PARAGRAPH SEPARATOR.
+		// They are both technically valid characters in JSON strings,
+		// but don't work in JSONP, which has to be evaluated as JavaScript,
+		// and can lead to security holes there. It is valid JSON to
+		// escape them, so we do so unconditionally.
+		// See https://en.wikipedia.org/wiki/JSON#Safety.
+		if c == '\u2028' || c == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, `\u202`...)
+			b = append(b, hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	b = append(b, '"')
+	return b
+}
+
+func marshalerEncoder(e *encodeState, v reflect.Value) error {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	m, ok := v.Interface().(json.Marshaler)
+	if !ok {
+		e.WriteString("null")
+		return nil
+	}
+	b, err := m.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("json: error calling MarshalJSON for type %v: %v", v.Type(), err)
+	}
+	return e.compact(b, v.Type())
+}
+
+func addrMarshalerEncoder(e *encodeState, v reflect.Value) error {
+	return marshalerEncoder(e, v.Addr())
+}
+
+// 输出 MarshalJSON() 的结果（去除空白并校验其合法性）
+func (e *encodeState) compact(b []byte, t reflect.Type) error {
+	if !e.escapeHTML {
+		if err := json.Compact(&e.Buffer, b); err != nil {
+			return fmt.Errorf("json: error calling MarshalJSON for type %v: %v", t, err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return fmt.Errorf("json: error calling MarshalJSON for type %v: %v", t, err)
+	}
+	json.HTMLEscape(&e.Buffer, buf.Bytes())
+	return nil
+}
+
+func textMarshalerEncoder(e *encodeState, v reflect.Value) error {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	m, ok := v.Interface().(encoding.TextMarshaler)
+	if !ok {
+		e.WriteString("null")
+		return nil
+	}
+	b, err := m.MarshalText()
+	if err != nil {
+		return fmt.Errorf("json: error calling MarshalText for type %v: %v", v.Type(), err)
+	}
+	e.string(string(b), e.escapeHTML)
+	return nil
+}
+
+func addrTextMarshalerEncoder(e *encodeState, v reflect.Value) error {
+	return textMarshalerEncoder(e, v.Addr())
+}
+
+// 值可寻址时使用 canAddrEnc，否则使用 elseEnc
+func newCondAddrEncoder(canAddrEnc, elseEnc encoderFunc) encoderFunc {
+	return func(e *encodeState, v reflect.Value) error {
+		if v.CanAddr() {
+			return canAddrEnc(e, v)
+		}
+		return elseEnc(e, v)
+	}
+}
+
+func newInterfaceEncoder(level int) encoderFunc {
+	return func(e *encodeState, v reflect.Value) error {
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		// 接口的动态类型只有在运行时才能确定
+		return typeEncoder(v.Elem().Type(), level)(e, v.Elem())
+	}
+}
+
+// 结构体中（保密级别允许输出的）域的 encoder
+type fieldEncoder struct {
+	index       []int
+	nameEscHTML string // `"` + HTMLEscape(alias) + `":`
+	nameNonEsc  string // `"` + alias + `":`
+
+	omitEmpty bool
+	isZero    func(reflect.Value) bool
+
+	enc encoderFunc
+}
+
+type structEncoder struct {
+	fields []fieldEncoder
+}
+
+func newStructEncoder(t reflect.Type, level int) encoderFunc {
+	cs, err := getSifter(t)
+	if err != nil {
+		return newErrorEncoder(err)
+	}
+
+	items := cs.visibleItems(level) // 已经按照安全级别筛选过的域
+
+	se := structEncoder{fields: make([]fieldEncoder, 0, len(items))}
+	for _, si := range items {
+		fe := fieldEncoder{
+			index:      si.index,
+			nameNonEsc: `"` + si.alias + `":`,
+			omitEmpty:  si.isOmitEmpty,
+			isZero:     si.isZero,
+		}
+		name := &encodeState{}
+		name.string(si.alias, true)
+		fe.nameEscHTML = name.String() + ":"
+
+		if si.isString {
+			fe.enc = newQuotedEncoder(si.typ)
+		} else {
+			fe.enc = typeEncoder(si.typ, level)
+		}
+		se.fields = append(se.fields, fe)
+	}
+	return se.encode
+}
+
+func (se structEncoder) encode(e *encodeState, v reflect.Value) error {
+	next := byte('{')
+	for i := range se.fields {
+		f := &se.fields[i]
+
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) || (f.isZero != nil && f.isZero(fv)) {
+			continue
+		}
+
+		e.WriteByte(next)
+		next = ','
+		if e.escapeHTML {
+			e.WriteString(f.nameEscHTML)
+		} else {
+			e.WriteString(f.nameNonEsc)
+		}
+		if err := f.enc(e, fv); err != nil {
+			return err
+		}
+	}
+	if next == '{' {
+		e.WriteString("{}")
+	} else {
+		e.WriteByte('}')
+	}
+	return nil
+}
+
+// json 标签的 `string` 选项：将值以字符串的形式输出
+func newQuotedEncoder(t reflect.Type) encoderFunc {
+	if t.Kind() == reflect.Ptr {
+		elemEnc := newQuotedEncoder(t.Elem())
+		return func(e *encodeState, v reflect.Value) error {
+			if v.IsNil() {
+				e.WriteString("null")
+				return nil
+			}
+			return elemEnc(e, v.Elem())
+		}
+	}
+
+	if t.Kind() == reflect.String {
+		return func(e *encodeState, v reflect.Value) error {
+			if v.Type() == numberType {
+				e.WriteByte('"')
+				if err := e.number(v.String()); err != nil {
+					return err
+				}
+				e.WriteByte('"')
+				return nil
+			}
+			inner := &encodeState{}
+			inner.string(v.String(), e.escapeHTML)
+			e.string(inner.String(), false)
+			return nil
+		}
+	}
+
+	enc := newTypeEncoder(t, 0, false)
+	return func(e *encodeState, v reflect.Value) error {
+		e.WriteByte('"')
+		if err := enc(e, v); err != nil {
+			return err
+		}
+		e.WriteByte('"')
+		return nil
+	}
+}
+
+func newMapEncoder(t reflect.Type, level int) encoderFunc {
+	if !isValidMapKeyType(t.Key()) {
+		return newErrorEncoder(fmt.Errorf("json: unsupported type: %v", t))
+	}
+	elemEnc := typeEncoder(t.Elem(), level)
+
+	return func(e *encodeState, v reflect.Value) error {
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		defer e.leave(v)
+
+		// 与 json 标准库一致，按照键排序输出
+		type keyValue struct {
+			key   string
+			value reflect.Value
+		}
+		kvs := make([]keyValue, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := resolveKeyName(iter.Key())
+			if err != nil {
+				return fmt.Errorf("json: encoding error for type %v: %v", t, err)
+			}
+			kvs = append(kvs, keyValue{key: k, value: iter.Value()})
+		}
+		sort.Slice(kvs, func(i, j int) bool {
+			return kvs[i].key < kvs[j].key
+		})
+
+		e.WriteByte('{')
+		for i, kv := range kvs {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			e.string(kv.key, e.escapeHTML)
+			e.WriteByte(':')
+			if err := elemEnc(e, kv.value); err != nil {
+				return err
+			}
+		}
+		e.WriteByte('}')
+		return nil
+	}
+}
+
+func newSliceEncoder(t reflect.Type, level int) encoderFunc {
+	// []byte 按照 base64 编码输出（除非元素类型自定义了序列化方式）
+	if t.Elem().Kind() == reflect.Uint8 {
+		p := reflect.PtrTo(t.Elem())
+		if !p.Implements(jsonMarshalerType) && !p.Implements(textMarshalerType) {
+			return bytesEncoder
+		}
+	}
+
+	arrayEnc := newArrayEncoder(t, level)
+	return func(e *encodeState, v reflect.Value) error {
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		defer e.leave(v)
+		return arrayEnc(e, v)
+	}
+}
+
+func bytesEncoder(e *encodeState, v reflect.Value) error {
+	if v.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	s := v.Bytes()
+	b := make([]byte, base64.StdEncoding.EncodedLen(len(s)))
+	base64.StdEncoding.Encode(b, s)
+	e.WriteByte('"')
+	e.Write(b)
+	e.WriteByte('"')
+	return nil
+}
+
+func newArrayEncoder(t reflect.Type, level int) encoderFunc {
+	elemEnc := typeEncoder(t.Elem(), level)
+	return func(e *encodeState, v reflect.Value) error {
+		e.WriteByte('[')
+		n := v.Len()
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			if err := elemEnc(e, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.WriteByte(']')
+		return nil
+	}
+}
+
+func newPtrEncoder(t reflect.Type, level int) encoderFunc {
+	elemEnc := typeEncoder(t.Elem(), level)
+	return func(e *encodeState, v reflect.Value) error {
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		defer e.leave(v)
+		return elemEnc(e, v.Elem())
+	}
+}
+
+// 判断字符串是否是合法的 json 数值
+// @refer `/encoding/json/encode.go`
+func isValidNumber(s string) bool {
+	// This function implements the JSON numbers grammar.
+	// See https://tools.ietf.org/html/rfc7159#section-6
+	// and https://www.json.org/img/number.png
+
+	if s == "" {
+		return false
+	}
+
+	// Optional -
+	if s[0] == '-' {
+		s = s[1:]
+		if s == "" {
+			return false
+		}
+	}
+
+	// Digits
+	switch {
+	default:
+		return false
+
+	case s[0] == '0':
+		s = s[1:]
+
+	case '1' <= s[0] && s[0] <= '9':
+		s = s[1:]
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+
+	// . followed by 1 or more digits.
+	if len(s) >= 2 && s[0] == '.' && '0' <= s[1] && s[1] <= '9' {
+		s = s[2:]
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+
+	// e or E followed by an optional - or + and
+	// 1 or more digits.
+	if len(s) >= 2 && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s[0] == '+' || s[0] == '-' {
+			s = s[1:]
+			if s == "" {
+				return false
+			}
+		}
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+
+	// Make sure we are at the end.
+	return s == ""
+}
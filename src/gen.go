@@ -0,0 +1,148 @@
+package api
+
+import (
+	"reflect"
+	"sync"
+)
+
+// 供 gosifter-gen（cmd/gosifter-gen）生成的代码以及生成工具本身使用的辅助函数。
+//
+// 生成的代码直接访问基本类型的域（字符串、数值、布尔值），嵌套的结构体按值展开；其余的域（指针、切片、映射、
+// 接口、自定义序列化方式的类型等）通过以下函数交由反射路径处理，以保证输出结果与反射路径完全一致。
+//
+// Note:
+//  传入的 ptr 均为指向结构体域的指针（即域值是可寻址的），与对结构体指针调用 SiftStruct()/MarshalLevel() 的
+//  行为一致。
+
+// 结构体域的标签（json 标签以及保密级别标签）解析结果
+type FieldTag struct {
+	Ignore    bool   // `json:"-"`
+	Alias     string // json 标签设置的别名；没有设置时为空
+	OmitEmpty bool
+	OmitZero  bool
+	AsString  bool
+	CLevel    int // 保密级别
+}
+
+// 按照 generateSifter() 的规则解析结构体域的标签
+func ParseFieldTag(tag reflect.StructTag) (FieldTag, error) {
+	var ft FieldTag
+	ft.Ignore, ft.Alias, ft.OmitEmpty, ft.OmitZero, ft.AsString = parseJsonTags(tag.Get("json"))
+
+	clevel, err := parseConfidentialTags(tag.Get(TAG_CONFIDENTIAL))
+	if err != nil {
+		return ft, err
+	}
+	ft.CLevel = clevel
+	return ft, nil
+}
+
+// 缓存的域值筛选方式（nil 表示直接输出原值）
+var fieldSifterCache sync.Map // map[reflect.Type]*valueSifter
+
+// 按照保密级别筛选某一个域的值（SiftStruct() 中域值的处理方式）
+func SiftField(ptr interface{}, maxConfidentialLevel int) (interface{}, error) {
+	rv := reflect.ValueOf(ptr).Elem()
+
+	vs, err := cachedValueSifter(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	if vs == nil {
+		return rv.Interface(), nil
+	}
+	return vs.sift(rv, maxConfidentialLevel, &siftState{})
+}
+
+// 获取（并缓存）某一个类型的值的筛选方式
+func cachedValueSifter(t reflect.Type) (*valueSifter, error) {
+	if cached, ok := fieldSifterCache.Load(t); ok {
+		return cached.(*valueSifter), nil
+	}
+	vs, err := getValueSifter(t)
+	if err != nil {
+		return nil, err
+	}
+	fieldSifterCache.Store(t, vs)
+	return vs, nil
+}
+
+// 按照 `string` 选项转换某一个域的值（SiftStruct() 中域值的处理方式）
+func QuoteField(ptr interface{}) (interface{}, error) {
+	return quoteValue(reflect.ValueOf(ptr).Elem())
+}
+
+// 判断某一个域的值是否是零值（omitzero）
+func IsZeroField(ptr interface{}) bool {
+	rv := reflect.ValueOf(ptr).Elem()
+	return zeroChecker(rv.Type())(rv)
+}
+
+// 生成的 MarshalJSONLevel() 在一次调用中共享的序列化状态：各个域复用同一个缓冲区（字符串、浮点数直接追加至
+// 调用者的缓冲区），嵌套深度以及循环检测的状态在域之间保持（与 MarshalLevel() 一致）。
+//
+// Note:
+//  通过 NewAppendState() 获取，使用完毕之后调用 Release() 归还；不能在多个 goroutine 中同时使用。
+type AppendState struct {
+	e encodeState
+}
+
+var appendStatePool sync.Pool
+
+// 获取一个 AppendState（与 MarshalLevel() 的输出选项一致）
+func NewAppendState() *AppendState {
+	if v := appendStatePool.Get(); v != nil {
+		return v.(*AppendState)
+	}
+	return &AppendState{e: encodeState{escapeHTML: true}}
+}
+
+// 归还 AppendState（之后不能再使用）
+func (st *AppendState) Release() {
+	appendStatePool.Put(st)
+}
+
+// 进入结构体指针 ptr（生成的方法在输出之前调用，与 MarshalLevel() 中的指针一样计入嵌套深度以及循环检测）
+func (st *AppendState) Enter(ptr interface{}) error {
+	return st.e.enter(reflect.ValueOf(ptr))
+}
+
+// 退出结构体指针 ptr（与 Enter() 成对调用）
+func (st *AppendState) Leave(ptr interface{}) {
+	st.e.leave(reflect.ValueOf(ptr))
+}
+
+// 将某一个域的值按照保密级别序列化之后追加至 b（MarshalLevel() 中域值的处理方式）
+func (st *AppendState) AppendField(b []byte, ptr interface{}, maxConfidentialLevel int) ([]byte, error) {
+	rv := reflect.ValueOf(ptr).Elem()
+	st.e.Reset()
+	if err := typeEncoder(rv.Type(), maxConfidentialLevel)(&st.e, rv); err != nil {
+		return nil, err
+	}
+	return append(b, st.e.Bytes()...), nil
+}
+
+// 将某一个域的值按照 `string` 选项序列化之后追加至 b
+func (st *AppendState) AppendQuotedField(b []byte, ptr interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(ptr).Elem()
+	st.e.Reset()
+	if err := newQuotedEncoder(rv.Type())(&st.e, rv); err != nil {
+		return nil, err
+	}
+	return append(b, st.e.Bytes()...), nil
+}
+
+// 将字符串序列化（包括转义）之后追加至 b
+func (st *AppendState) AppendString(b []byte, s string) []byte {
+	return appendString(b, s, st.e.escapeHTML)
+}
+
+// 将浮点数序列化之后追加至 b（bits 为 32 或者 64）
+func (st *AppendState) AppendFloat(b []byte, f float64, bits int) ([]byte, error) {
+	return appendFloat(b, f, bits)
+}
+
+// 将字符串序列化（包括转义）之后追加至 b（供生成工具本身使用，输出选项与 NewAppendState() 一致）
+func AppendString(b []byte, s string) []byte {
+	return appendString(b, s, true)
+}
@@ -0,0 +1,492 @@
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 按照保密级别输出 MessagePack（https://github.com/msgpack/msgpack/blob/master/spec.md）。
+//
+// 结构体输出为 map（键的顺序与结构体域的声明顺序一致，保密级别高于 maxConfidentialLevel 的域被筛除），域的选择
+// 以及键名优先使用 msgpack 标签（`msgpack:"name,omitempty"`，`msgpack:"-"` 表示忽略；`json:"-"` 但设置了
+// msgpack 标签的域依然输出），没有 msgpack 标签时使用 json 标签的别名以及 omitempty/omitzero 选项（json 标签中的
+// string 选项不生效）。设置了别名的嵌入结构体作为一个键嵌套输出，否则展开至当前层；同名键按照与 json 相同的规则
+// 处理（层次较浅的优先，同一层次中设置了标签的优先，无法区分的全部忽略）。其余类型的输出方式：
+//  1. 实现了 MsgpackMarshaler 的类型直接输出 MarshalMsgpack() 的结果；
+//  2. time.Time 输出为 timestamp 扩展类型（-1）；
+//  3. 实现了 json.Marshaler 的类型将 MarshalJSON() 的结果解析之后输出，实现了 encoding.TextMarshaler 的类型输出为 str；
+//  4. []byte（以及 [N]byte）输出为 bin，映射的键与 json 一样转换为字符串并排序；
+//  5. chan、func、complex 等无法输出的类型返回错误。
+
+// 自定义 MessagePack 序列化方式的类型（返回完整的 MessagePack 编码）
+type MsgpackMarshaler interface {
+	MarshalMsgpack() ([]byte, error)
+}
+
+var (
+	msgpackMarshalerType = reflect.TypeOf((*MsgpackMarshaler)(nil)).Elem()
+	timeType             = reflect.TypeOf(time.Time{})
+)
+
+// 结构体中的域（MessagePack 输出时的键名以及选项）
+type msgpackField struct {
+	index     []int
+	name      string
+	omitEmpty bool
+	isZero    func(reflect.Value) bool
+}
+
+// 结构体类型（的 sifter）对应的 MessagePack 输出计划
+type msgpackPlan struct {
+	levelFields [CONFIDENTIAL_LEVEL_MAX + 1][]*msgpackField // 各个保密级别下可见的域
+}
+
+var msgpackPlanCache sync.Map // map[*cachedSifter]*msgpackPlan
+
+type msgpackState struct {
+	siftState
+	b []byte
+}
+
+// api function
+//
+// 按照保密级别对任意值进行 MessagePack 序列化。
+//
+// @param
+//  v - 需要执行筛选/脱敏的值（结构体、指针、切片、映射等均可）
+//  maxConfidentialLevel - 最高允许的安全等级（高于此等级的将被筛除）
+func MarshalMsgpackLevel(v interface{}, maxConfidentialLevel int) ([]byte, error) {
+	e := &msgpackState{}
+	if v == nil {
+		e.writeNil()
+		return e.b, nil
+	}
+	if err := e.encode(reflect.ValueOf(v), maxConfidentialLevel); err != nil {
+		return nil, err
+	}
+	return e.b, nil
+}
+
+func getMsgpackPlan(cs *cachedSifter) (*msgpackPlan, error) {
+	if plan, ok := msgpackPlanCache.Load(cs); ok {
+		return plan.(*msgpackPlan), nil
+	}
+
+	fields, err := formatFields(cs.typ, msgpackTagAlias)
+	if err != nil {
+		return nil, err
+	}
+	plan := &msgpackPlan{}
+	for _, ff := range fields {
+		f := &msgpackField{index: ff.index, name: ff.name}
+		if tag, ok := ff.sf.Tag.Lookup("msgpack"); ok {
+			_, _, f.omitEmpty, _, _ = parseJsonTags(tag)
+		} else {
+			_, _, omitempty, omitzero, _ := parseJsonTags(ff.sf.Tag.Get("json"))
+			f.omitEmpty = omitempty
+			if omitzero {
+				f.isZero = zeroChecker(ff.sf.Type)
+			}
+		}
+		for level := ff.cLevel; level <= CONFIDENTIAL_LEVEL_MAX; level++ {
+			plan.levelFields[level] = append(plan.levelFields[level], f)
+		}
+	}
+	msgpackPlanCache.Store(cs, plan)
+	return plan, nil
+}
+
+// 结构体域在 MessagePack 中的别名：优先使用 msgpack 标签，没有 msgpack 标签（或者其中没有设置别名）时使用 json 标签
+func msgpackTagAlias(sf reflect.StructField) (alias string, ignore bool) {
+	ignore, alias, _, _, _ = parseJsonTags(sf.Tag.Get("json"))
+	if tag, ok := sf.Tag.Lookup("msgpack"); ok {
+		var malias string
+		if ignore, malias, _, _, _ = parseJsonTags(tag); malias != "" {
+			alias = malias
+		}
+	}
+	return alias, ignore
+}
+
+func (e *msgpackState) encode(rv reflect.Value, level int) error {
+	t := rv.Type()
+
+	// 指针接收者实现的序列化方法只在值可寻址时生效（与 json 一致）
+	if t.Kind() != reflect.Ptr && rv.CanAddr() && isMsgpackMarshalerType(reflect.PtrTo(t)) {
+		return e.encodeMarshaler(rv.Addr())
+	}
+	if isMsgpackMarshalerType(t) {
+		return e.encodeMarshaler(rv)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		e.writeBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(rv.Uint())
+	case reflect.Float32:
+		e.b = append(e.b, 0xca)
+		e.b = appendUint32(e.b, math.Float32bits(float32(rv.Float())))
+	case reflect.Float64:
+		e.b = append(e.b, 0xcb)
+		e.b = appendUint64(e.b, math.Float64bits(rv.Float()))
+	case reflect.String:
+		if t == numberType {
+			return e.encodeNumber(rv.String())
+		}
+		e.writeString(rv.String())
+	case reflect.Interface:
+		if rv.IsNil() {
+			e.writeNil()
+			return nil
+		}
+		return e.encode(rv.Elem(), level)
+	case reflect.Ptr:
+		if rv.IsNil() {
+			e.writeNil()
+			return nil
+		}
+		if err := e.enter(rv); err != nil {
+			return err
+		}
+		defer e.leave(rv)
+		return e.encode(rv.Elem(), level)
+	case reflect.Struct:
+		return e.encodeStruct(rv, level)
+	case reflect.Map:
+		return e.encodeMap(rv, level)
+	case reflect.Slice:
+		if rv.IsNil() {
+			e.writeNil()
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 && !isMsgpackMarshalerType(reflect.PtrTo(t.Elem())) {
+			e.writeBin(rv.Bytes())
+			return nil
+		}
+		if err := e.enter(rv); err != nil {
+			return err
+		}
+		defer e.leave(rv)
+		return e.encodeArray(rv, level)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && !isMsgpackMarshalerType(reflect.PtrTo(t.Elem())) {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			e.writeBin(b)
+			return nil
+		}
+		return e.encodeArray(rv, level)
+	default:
+		return fmt.Errorf("msgpack: unsupported type: %v", t)
+	}
+	return nil
+}
+
+// 是否为 MessagePack 有专门输出方式的类型（MsgpackMarshaler、time.Time、json.Marshaler 以及 encoding.TextMarshaler）
+func isMsgpackMarshalerType(t reflect.Type) bool {
+	return t == timeType || t.Implements(msgpackMarshalerType) || isMarshalerType(t)
+}
+
+func (e *msgpackState) encodeMarshaler(rv reflect.Value) error {
+	if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		e.writeNil()
+		return nil
+	}
+
+	switch m := rv.Interface().(type) {
+	case MsgpackMarshaler:
+		b, err := m.MarshalMsgpack()
+		if err != nil {
+			return fmt.Errorf("msgpack: error calling MarshalMsgpack for type %v: %v", rv.Type(), err)
+		}
+		e.b = append(e.b, b...)
+	case time.Time:
+		e.writeTime(m)
+	case *time.Time:
+		e.writeTime(*m)
+	case json.Marshaler:
+		b, err := m.MarshalJSON()
+		if err != nil {
+			return fmt.Errorf("json: error calling MarshalJSON for type %v: %v", rv.Type(), err)
+		}
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return fmt.Errorf("json: error calling MarshalJSON for type %v: %v", rv.Type(), err)
+		}
+		if v == nil {
+			e.writeNil()
+			return nil
+		}
+		return e.encode(reflect.ValueOf(v), CONFIDENTIAL_LEVEL_MAX)
+	case encoding.TextMarshaler:
+		b, err := m.MarshalText()
+		if err != nil {
+			return fmt.Errorf("json: error calling MarshalText for type %v: %v", rv.Type(), err)
+		}
+		e.writeString(string(b))
+	}
+	return nil
+}
+
+// json.Number：整数按照 int/uint 输出，其余按照 float64 输出
+func (e *msgpackState) encodeNumber(s string) error {
+	if s == "" {
+		s = "0"
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		e.writeInt(i)
+		return nil
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		e.writeUint(u)
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("msgpack: invalid number literal %q", s)
+	}
+	e.b = append(e.b, 0xcb)
+	e.b = appendUint64(e.b, math.Float64bits(f))
+	return nil
+}
+
+func (e *msgpackState) encodeStruct(rv reflect.Value, level int) error {
+	cs, err := getSifter(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	var fields []*msgpackField
+	if level >= CONFIDENTIAL_LEVEL0 {
+		if level > CONFIDENTIAL_LEVEL_MAX {
+			level = CONFIDENTIAL_LEVEL_MAX
+		}
+		plan, err := getMsgpackPlan(cs)
+		if err != nil {
+			return err
+		}
+		fields = plan.levelFields[level]
+	}
+
+	// map 的头部需要预先知道元素的个数
+	values := make([]reflect.Value, len(fields))
+	n := 0
+	for i, f := range fields {
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok || !fv.IsValid() || (f.omitEmpty && isEmptyValue(fv)) || (f.isZero != nil && f.isZero(fv)) || !fv.CanInterface() {
+			continue
+		}
+		values[i] = fv
+		n++
+	}
+
+	e.writeMapHeader(n)
+	for i, f := range fields {
+		if !values[i].IsValid() {
+			continue
+		}
+		e.writeString(f.name)
+		if err := e.encode(values[i], level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *msgpackState) encodeMap(rv reflect.Value, level int) error {
+	if rv.IsNil() {
+		e.writeNil()
+		return nil
+	}
+	if !isValidMapKeyType(rv.Type().Key()) {
+		return fmt.Errorf("msgpack: unsupported type: %v", rv.Type())
+	}
+	if err := e.enter(rv); err != nil {
+		return err
+	}
+	defer e.leave(rv)
+
+	type keyValue struct {
+		key   string
+		value reflect.Value
+	}
+	kvs := make([]keyValue, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k, err := resolveKeyName(iter.Key())
+		if err != nil {
+			return fmt.Errorf("msgpack: encoding error for type %v: %v", rv.Type(), err)
+		}
+		kvs = append(kvs, keyValue{key: k, value: iter.Value()})
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].key < kvs[j].key
+	})
+
+	e.writeMapHeader(len(kvs))
+	for _, kv := range kvs {
+		e.writeString(kv.key)
+		if err := e.encode(kv.value, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *msgpackState) encodeArray(rv reflect.Value, level int) error {
+	n := rv.Len()
+	switch {
+	case n < 16:
+		e.b = append(e.b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.b = append(e.b, 0xdc)
+		e.b = appendUint16(e.b, uint16(n))
+	default:
+		e.b = append(e.b, 0xdd)
+		e.b = appendUint32(e.b, uint32(n))
+	}
+	for i := 0; i < n; i++ {
+		if err := e.encode(rv.Index(i), level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *msgpackState) writeNil() {
+	e.b = append(e.b, 0xc0)
+}
+
+func (e *msgpackState) writeBool(v bool) {
+	if v {
+		e.b = append(e.b, 0xc3)
+	} else {
+		e.b = append(e.b, 0xc2)
+	}
+}
+
+// 按照最短的编码输出整数
+func (e *msgpackState) writeInt(v int64) {
+	switch {
+	case v >= 0:
+		e.writeUint(uint64(v))
+	case v >= -32:
+		e.b = append(e.b, byte(v))
+	case v >= math.MinInt8:
+		e.b = append(e.b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		e.b = append(e.b, 0xd1)
+		e.b = appendUint16(e.b, uint16(v))
+	case v >= math.MinInt32:
+		e.b = append(e.b, 0xd2)
+		e.b = appendUint32(e.b, uint32(v))
+	default:
+		e.b = append(e.b, 0xd3)
+		e.b = appendUint64(e.b, uint64(v))
+	}
+}
+
+func (e *msgpackState) writeUint(v uint64) {
+	switch {
+	case v <= 0x7f:
+		e.b = append(e.b, byte(v))
+	case v <= math.MaxUint8:
+		e.b = append(e.b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		e.b = append(e.b, 0xcd)
+		e.b = appendUint16(e.b, uint16(v))
+	case v <= math.MaxUint32:
+		e.b = append(e.b, 0xce)
+		e.b = appendUint32(e.b, uint32(v))
+	default:
+		e.b = append(e.b, 0xcf)
+		e.b = appendUint64(e.b, v)
+	}
+}
+
+func (e *msgpackState) writeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.b = append(e.b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.b = append(e.b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.b = append(e.b, 0xda)
+		e.b = appendUint16(e.b, uint16(n))
+	default:
+		e.b = append(e.b, 0xdb)
+		e.b = appendUint32(e.b, uint32(n))
+	}
+	e.b = append(e.b, s...)
+}
+
+func (e *msgpackState) writeBin(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.b = append(e.b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.b = append(e.b, 0xc5)
+		e.b = appendUint16(e.b, uint16(n))
+	default:
+		e.b = append(e.b, 0xc6)
+		e.b = appendUint32(e.b, uint32(n))
+	}
+	e.b = append(e.b, b...)
+}
+
+func (e *msgpackState) writeMapHeader(n int) {
+	switch {
+	case n < 16:
+		e.b = append(e.b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.b = append(e.b, 0xde)
+		e.b = appendUint16(e.b, uint16(n))
+	default:
+		e.b = append(e.b, 0xdf)
+		e.b = appendUint32(e.b, uint32(n))
+	}
+}
+
+// timestamp 扩展类型（type -1），按照所需的精度选择 32/64/96 位的格式
+func (e *msgpackState) writeTime(t time.Time) {
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		e.b = append(e.b, 0xd6, 0xff)
+		e.b = appendUint32(e.b, uint32(sec))
+	case sec>>34 == 0:
+		e.b = append(e.b, 0xd7, 0xff)
+		e.b = appendUint64(e.b, uint64(nsec)<<34|uint64(sec))
+	default:
+		e.b = append(e.b, 0xc7, 12, 0xff)
+		e.b = appendUint32(e.b, uint32(nsec))
+		e.b = appendUint64(e.b, uint64(sec))
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,401 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// 一次遍历同时输出所有保密级别（CONFIDENTIAL_LEVEL0 ~ CONFIDENTIAL_LEVEL_MAX）的 json 序列化结果。
+//
+// 遍历过程中携带一个保密级别的集合（levelMask），表示当前的值在哪些级别下可见：结构体的域只对不低于其保密级别
+// 的级别可见；与保密级别无关的值（其类型中不存在受限的域，也不存在接口）只序列化一次，得到的片段直接追加至
+// 所有可见的级别。
+
+// 保密级别的集合（第 i 位表示 level i）
+type levelMask uint
+
+const allLevels levelMask = 1<<(CONFIDENTIAL_LEVEL_MAX+1) - 1
+
+// 不低于 level 的所有级别
+func levelsFrom(level int) levelMask {
+	return allLevels &^ (1<<uint(level) - 1)
+}
+
+type multiEncodeState struct {
+	siftState
+
+	levels [CONFIDENTIAL_LEVEL_MAX + 1]encodeState // 各个级别的输出
+	frag   encodeState                             // 共享片段的输出
+}
+
+type multiEncoderFunc func(m *multiEncodeState, v reflect.Value, mask levelMask) error
+
+var multiEncoderCache sync.Map // map[reflect.Type]multiEncoderFunc
+
+// 类型的序列化结果是否与保密级别有关
+var levelDependentCache sync.Map // map[reflect.Type]bool
+
+// api function
+//
+// 一次遍历输出所有保密级别下的 json 序列化结果，结果的下标即保密级别；每个级别的结果与 MarshalLevel() 一致。
+func MarshalAllLevels(v interface{}) ([][]byte, error) {
+	m := newMultiEncodeState()
+	defer multiEncodeStatePool.Put(m)
+
+	if v == nil {
+		m.writeString(allLevels, "null")
+	} else {
+		rv := reflect.ValueOf(v)
+		if err := multiTypeEncoder(rv.Type())(m, rv, allLevels); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([][]byte, len(m.levels))
+	for l := range m.levels {
+		out[l] = append([]byte(nil), m.levels[l].Bytes()...)
+	}
+	return out, nil
+}
+
+// 复用的 multiEncodeState（及其缓冲区）
+var multiEncodeStatePool sync.Pool
+
+func newMultiEncodeState() *multiEncodeState {
+	if v := multiEncodeStatePool.Get(); v != nil {
+		m := v.(*multiEncodeState)
+		m.siftState = siftState{}
+		m.frag.Reset()
+		for l := range m.levels {
+			m.levels[l].Reset()
+		}
+		return m
+	}
+	m := &multiEncodeState{}
+	m.frag.escapeHTML = true
+	for l := range m.levels {
+		m.levels[l].escapeHTML = true
+	}
+	return m
+}
+
+func (m *multiEncodeState) write(mask levelMask, b []byte) {
+	for l := range m.levels {
+		if mask&(1<<uint(l)) != 0 {
+			m.levels[l].Write(b)
+		}
+	}
+}
+
+func (m *multiEncodeState) writeString(mask levelMask, s string) {
+	for l := range m.levels {
+		if mask&(1<<uint(l)) != 0 {
+			m.levels[l].WriteString(s)
+		}
+	}
+}
+
+func (m *multiEncodeState) writeByte(mask levelMask, c byte) {
+	for l := range m.levels {
+		if mask&(1<<uint(l)) != 0 {
+			m.levels[l].WriteByte(c)
+		}
+	}
+}
+
+// 获取（或者编译）某一个类型的多级别 encoder（递归类型的处理方式与 typeEncoder() 一致）
+func multiTypeEncoder(t reflect.Type) multiEncoderFunc {
+	if f, ok := multiEncoderCache.Load(t); ok {
+		return f.(multiEncoderFunc)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		real multiEncoderFunc
+	)
+	wg.Add(1)
+	f, loaded := multiEncoderCache.LoadOrStore(t, multiEncoderFunc(func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+		wg.Wait()
+		return real(m, v, mask)
+	}))
+	if loaded {
+		return f.(multiEncoderFunc)
+	}
+
+	real = newMultiEncoder(t, true)
+	wg.Done()
+	multiEncoderCache.Store(t, real)
+	return real
+}
+
+// @param
+//  allowAddr - 是否允许在值可寻址时使用指针接收者实现的序列化方法（参考 newTypeEncoder()）
+func newMultiEncoder(t reflect.Type, allowAddr bool) multiEncoderFunc {
+	if !isLevelDependent(t) {
+		return newFragmentEncoder(typeEncoder(t, CONFIDENTIAL_LEVEL_MAX))
+	}
+	if t.Kind() != reflect.Ptr && allowAddr && isMarshalerType(reflect.PtrTo(t)) {
+		// 可寻址时按照其指针的序列化方法输出（与保密级别无关），否则按照其自身的类型输出
+		addrEnc := newFragmentEncoder(typeEncoder(t, CONFIDENTIAL_LEVEL_MAX))
+		elseEnc := newMultiEncoder(t, false)
+		return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+			if v.CanAddr() {
+				return addrEnc(m, v, mask)
+			}
+			return elseEnc(m, v, mask)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return multiInterfaceEncoder
+	case reflect.Struct:
+		return newMultiStructEncoder(t)
+	case reflect.Map:
+		return newMultiMapEncoder(t)
+	case reflect.Slice:
+		return newMultiSliceEncoder(t)
+	case reflect.Array:
+		return newMultiArrayEncoder(t)
+	case reflect.Ptr:
+		return newMultiPtrEncoder(t)
+	default:
+		return newFragmentEncoder(typeEncoder(t, CONFIDENTIAL_LEVEL_MAX))
+	}
+}
+
+// 与保密级别无关的值：只序列化一次，再追加至所有可见的级别
+func newFragmentEncoder(enc encoderFunc) multiEncoderFunc {
+	return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+		m.frag.Reset()
+		if err := enc(&m.frag, v); err != nil {
+			return err
+		}
+		m.write(mask, m.frag.Bytes())
+		return nil
+	}
+}
+
+// 判断类型的序列化结果是否与保密级别有关：从此类型出发（经过结构体的域、指针、切片/数组的元素以及映射的值）
+// 能够到达接口类型，或者存在保密级别高于 CONFIDENTIAL_LEVEL0 的结构体域。
+func isLevelDependent(t reflect.Type) bool {
+	if dep, ok := levelDependentCache.Load(t); ok {
+		return dep.(bool)
+	}
+
+	visited := map[reflect.Type]bool{}
+	var walk func(t reflect.Type) bool
+	walk = func(t reflect.Type) bool {
+		if visited[t] {
+			return false
+		}
+		visited[t] = true
+
+		if isMarshalerType(t) {
+			return false
+		}
+		switch t.Kind() {
+		case reflect.Interface:
+			return true
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			return walk(t.Elem())
+		case reflect.Struct:
+			cs, err := getSifter(t)
+			if err != nil {
+				// 交由多级别的结构体 encoder 报告错误
+				return true
+			}
+			for _, si := range cs.sifterItems {
+				if si.cLevel > CONFIDENTIAL_LEVEL0 || walk(si.typ) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	dep := walk(t)
+	levelDependentCache.Store(t, dep)
+	return dep
+}
+
+func multiInterfaceEncoder(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+	if v.IsNil() {
+		m.writeString(mask, "null")
+		return nil
+	}
+	// 接口的动态类型只有在运行时才能确定
+	return multiTypeEncoder(v.Elem().Type())(m, v.Elem(), mask)
+}
+
+// 结构体中的域（所有级别中可见的域）
+type multiFieldEncoder struct {
+	index       []int
+	nameEscHTML string    // `"` + HTMLEscape(alias) + `":`
+	mask        levelMask // 此域可见的级别
+
+	omitEmpty bool
+	isZero    func(reflect.Value) bool
+
+	enc multiEncoderFunc
+}
+
+func newMultiStructEncoder(t reflect.Type) multiEncoderFunc {
+	cs, err := getSifter(t)
+	if err != nil {
+		return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+			return err
+		}
+	}
+
+	fields := make([]multiFieldEncoder, 0, len(cs.sifterItems))
+	for _, si := range cs.sifterItems {
+		fe := multiFieldEncoder{
+			index:     si.index,
+			mask:      levelsFrom(si.cLevel),
+			omitEmpty: si.isOmitEmpty,
+			isZero:    si.isZero,
+		}
+		name := &encodeState{}
+		name.string(si.alias, true)
+		fe.nameEscHTML = name.String() + ":"
+
+		if si.isString {
+			fe.enc = newFragmentEncoder(newQuotedEncoder(si.typ))
+		} else {
+			fe.enc = multiTypeEncoder(si.typ)
+		}
+		fields = append(fields, fe)
+	}
+
+	return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+		var opened levelMask // 已经输出了 `{` 的级别
+		for i := range fields {
+			f := &fields[i]
+			active := mask & f.mask
+			if active == 0 {
+				continue
+			}
+
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || (f.omitEmpty && isEmptyValue(fv)) || (f.isZero != nil && f.isZero(fv)) {
+				continue
+			}
+
+			m.writeByte(active&^opened, '{')
+			m.writeByte(active&opened, ',')
+			opened |= active
+			m.writeString(active, f.nameEscHTML)
+			if err := f.enc(m, fv, active); err != nil {
+				return err
+			}
+		}
+		m.writeString(mask&^opened, "{}")
+		m.writeByte(mask&opened, '}')
+		return nil
+	}
+}
+
+func newMultiMapEncoder(t reflect.Type) multiEncoderFunc {
+	if !isValidMapKeyType(t.Key()) {
+		return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+			return fmt.Errorf("json: unsupported type: %v", t)
+		}
+	}
+	elemEnc := multiTypeEncoder(t.Elem())
+
+	return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+		if v.IsNil() {
+			m.writeString(mask, "null")
+			return nil
+		}
+		if err := m.enter(v); err != nil {
+			return err
+		}
+		defer m.leave(v)
+
+		// 与 json 标准库一致，按照键排序输出
+		type keyValue struct {
+			key   string
+			value reflect.Value
+		}
+		kvs := make([]keyValue, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := resolveKeyName(iter.Key())
+			if err != nil {
+				return fmt.Errorf("json: encoding error for type %v: %v", t, err)
+			}
+			kvs = append(kvs, keyValue{key: k, value: iter.Value()})
+		}
+		sort.Slice(kvs, func(i, j int) bool {
+			return kvs[i].key < kvs[j].key
+		})
+
+		m.writeByte(mask, '{')
+		for i, kv := range kvs {
+			if i > 0 {
+				m.writeByte(mask, ',')
+			}
+			m.frag.Reset()
+			m.frag.string(kv.key, true)
+			m.frag.WriteByte(':')
+			m.write(mask, m.frag.Bytes())
+			if err := elemEnc(m, kv.value, mask); err != nil {
+				return err
+			}
+		}
+		m.writeByte(mask, '}')
+		return nil
+	}
+}
+
+func newMultiSliceEncoder(t reflect.Type) multiEncoderFunc {
+	arrayEnc := newMultiArrayEncoder(t)
+	return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+		if v.IsNil() {
+			m.writeString(mask, "null")
+			return nil
+		}
+		if err := m.enter(v); err != nil {
+			return err
+		}
+		defer m.leave(v)
+		return arrayEnc(m, v, mask)
+	}
+}
+
+func newMultiArrayEncoder(t reflect.Type) multiEncoderFunc {
+	elemEnc := multiTypeEncoder(t.Elem())
+	return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+		m.writeByte(mask, '[')
+		n := v.Len()
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				m.writeByte(mask, ',')
+			}
+			if err := elemEnc(m, v.Index(i), mask); err != nil {
+				return err
+			}
+		}
+		m.writeByte(mask, ']')
+		return nil
+	}
+}
+
+func newMultiPtrEncoder(t reflect.Type) multiEncoderFunc {
+	elemEnc := multiTypeEncoder(t.Elem())
+	return func(m *multiEncodeState, v reflect.Value, mask levelMask) error {
+		if v.IsNil() {
+			m.writeString(mask, "null")
+			return nil
+		}
+		if err := m.enter(v); err != nil {
+			return err
+		}
+		defer m.leave(v)
+		return elemEnc(m, v.Elem(), mask)
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// 保持结构体域声明顺序的筛选结果（嵌套的结构体同样输出为 OrderedMap；映射依然输出为 map[string]interface{}，
+// 与 json 标准库一样按照键排序后序列化）。
+//
+// Note:
+//  json 序列化的结果与 MarshalLevel() 一致（键的顺序与结构体域的声明顺序一致）。
+type OrderedMap []OrderedItem
+
+type OrderedItem struct {
+	Key   string
+	Value interface{}
+}
+
+// 获取某一个键对应的值
+func (om OrderedMap) Get(key string) (interface{}, bool) {
+	for i := range om {
+		if om[i].Key == key {
+			return om[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// 按照顺序返回所有的键
+func (om OrderedMap) Keys() []string {
+	keys := make([]string, len(om))
+	for i := range om {
+		keys[i] = om[i].Key
+	}
+	return keys
+}
+
+// 转换为 map（嵌套的 OrderedMap 不做转换）
+func (om OrderedMap) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(om))
+	for i := range om {
+		m[om[i].Key] = om[i].Value
+	}
+	return m
+}
+
+// 按照顺序输出 json 对象；nil 输出为 null
+func (om OrderedMap) MarshalJSON() ([]byte, error) {
+	if om == nil {
+		return []byte("null"), nil
+	}
+
+	var buf bytes.Buffer
+	e := &encodeState{}
+	buf.WriteByte('{')
+	for i := range om {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		e.Reset()
+		e.string(om[i].Key, true)
+		buf.Write(e.Bytes())
+		buf.WriteByte(':')
+
+		b, err := json.Marshal(om[i].Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// 对结构体（或者其指针）进行筛选，结果保持结构体域的声明顺序。
+func (sifter *Sifter) SiftOrdered(s interface{}, maxConfidentialLevel int) (OrderedMap, error) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	return sifter.cs.siftOrdered(rv, maxConfidentialLevel, &siftState{ordered: true})
+}
+
+func (cs *cachedSifter) siftOrdered(rv reflect.Value, maxConfidentialLevel int, st *siftState) (OrderedMap, error) {
+	items := cs.visibleItems(maxConfidentialLevel)
+
+	out := make(OrderedMap, 0, len(items))
+	for _, si := range items {
+		v, ok, err := si.sift(rv, maxConfidentialLevel, st)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, OrderedItem{Key: si.alias, Value: v})
+		}
+	}
+	return out, nil
+}
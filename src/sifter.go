@@ -1,273 +1,1058 @@
 package api
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode"
 )
 
 type sifterItem struct {
-	index int    // 索引值 [0, reflect.Valueof(s).NumField())
-	field string // 结构体域名称
+	index []int        // 索引路径，即依次通过 rv.Field(index[0]).Field(index[1])... 获取值（嵌入结构体的域需要逐层索引）
+	field string       // 结构体域名称
+	typ   reflect.Type // 结构体域的类型
 
-	isAnonymous bool          // 结构体嵌套的是否是匿名域（anonymous struct field）
-	embedded    *cachedSifter // 结构体嵌套的间接引用
+	alias       string                   // 序列化时采取的别名
+	isTagged    bool                     // 别名是否来自 json 标签（用于处理嵌入结构体之间的同名域）
+	isOmitEmpty bool                     // json 序列化选项（omitempty）
+	isZero      func(reflect.Value) bool // json 序列化选项（omitzero）；nil 表示没有设置此选项
+	isString    bool                     // json 序列化选项（string）
 
-	alias       string // 序列化时采取的别名
-	isOmitEmpty bool   // json 序列化选项（omitempty）
+	cLevel int // confidential level（保密级别）；嵌入结构体的域取索引路径上的最高保密级别
 
-	cLevel int // confidential level（保密级别）
+	yaml *yamlTag // yaml 标签（参考 MarshalYAMLLevel()）；nil 表示没有 yaml 标签
+
+	value *valueSifter // 域值的筛选方式；nil 表示直接输出原值
 }
 
 type cachedSifter struct {
-	sifterItems []*sifterItem
+	typ         reflect.Type  // 对应的结构体类型
+	sifterItems []*sifterItem // 按照结构体域的声明顺序排列（嵌入结构体的域已展开）
+
+	// 各个保密级别下可见的域（构建时预先剔除高于该级别的域），即 (type, level) 对应的筛选计划
+	levelItems [CONFIDENTIAL_LEVEL_MAX + 1][]*sifterItem
+}
+
+// 获取某一个保密级别下可见的域；低于 CONFIDENTIAL_LEVEL0 时没有可见的域，高于 CONFIDENTIAL_LEVEL_MAX 时按照最高级别处理。
+func (cs *cachedSifter) visibleItems(maxConfidentialLevel int) []*sifterItem {
+	if maxConfidentialLevel < CONFIDENTIAL_LEVEL0 {
+		return nil
+	}
+	if maxConfidentialLevel > CONFIDENTIAL_LEVEL_MAX {
+		maxConfidentialLevel = CONFIDENTIAL_LEVEL_MAX
+	}
+	return cs.levelItems[maxConfidentialLevel]
 }
 
+// 需要进一步筛选的域值的类别
+const (
+	vkStruct        = iota // 结构体
+	vkPtr                  // 指针（可能是多级指针）
+	vkSlice                // 切片或者数组
+	vkMap                  // 映射（map）
+	vkIface                // 接口（运行时根据动态类型获取筛选方式）
+	vkAddrMarshaler        // 指针接收者实现了 json.Marshaler/encoding.TextMarshaler 的值（可寻址时按照其指针输出，否则按照 elem 筛选）
+)
+
+// 域值的筛选方式
+type valueSifter struct {
+	kind     int
+	elem     *valueSifter  // vkPtr/vkSlice/vkMap: 指针指向的值（或者切片/数组元素、映射值）的筛选方式
+	embedded *cachedSifter // vkStruct: 结构体的 sifter
+}
+
+// 缓存的 sifter（copy-on-write：读取时无锁，写入时加锁并复制整个映射之后再原子地替换）
 var sifterCache struct {
-	sync.RWMutex
-	m map[reflect.Type]cachedSifter
+	sync.Mutex              // 仅用于写入
+	m          atomic.Value // map[reflect.Type]*cachedSifter
+}
+
+// 是否拒绝为尚未缓存（注册）的类型新建 sifter（0/1: 否/是）
+var rejectUnregistered int32
+
+// 筛选过程中的运行时状态（用于检测嵌套深度以及循环引用）
+type siftState struct {
+	depth    int
+	ptrSeen  map[siftCycleKey]struct{}
+	ordered  bool // 嵌套的结构体是否输出为 OrderedMap
+	deepCopy bool // 是否深拷贝直接输出的原值（参考 deepCopy()）
 }
 
-type sifterItemCtx struct {
-	rv reflect.Value // si索引指向的所属的值（reflect value），即可以通过 rv.Field(si.index) 获取值
-	in []string      // 嵌入结构体的父层结构体名称列表（忽略所有的 anonymous）；如果没有父层结构则是 nil
-	si *sifterItem
+// 指针/切片/映射的标识；切片需要区分长度（如 s[:1] 和 s[:2] 并不构成循环引用）
+type siftCycleKey struct {
+	ptr uintptr
+	len int
 }
 
-func (cs *cachedSifter) SiftStruct(s interface{}, maxConfidentialLevel int) (map[string]interface{}, error) {
-	// root reflect value (value of @param s)
-	rrv := reflect.ValueOf(s)
+// 进入下一层嵌套；嵌套过深或者检测到循环引用时返回错误。
+func (st *siftState) enter(rv reflect.Value) error {
+	if st.depth++; st.depth > MAX_SIFT_DEPTH {
+		st.depth--
+		return fmt.Errorf("abort due to too deep nesting (limit %d)", MAX_SIFT_DEPTH)
+	}
 
-	siList := make([]sifterItemCtx, 0, len(cs.sifterItems))
-	for _, si := range cs.sifterItems {
-		siList = append(siList, sifterItemCtx{
-			rv: rrv,
-			in: nil,
-			si: si,
-		})
+	if key, ok := cycleKeyOf(rv, st.depth); ok {
+		if _, seen := st.ptrSeen[key]; seen {
+			st.depth--
+			return fmt.Errorf("abort due to cycle detected via %v", rv.Type())
+		}
+		if st.ptrSeen == nil {
+			st.ptrSeen = make(map[siftCycleKey]struct{})
+		}
+		st.ptrSeen[key] = struct{}{}
 	}
+	return nil
+}
+
+// 退出当前层嵌套（与 enter() 成对调用）
+func (st *siftState) leave(rv reflect.Value) {
+	if key, ok := cycleKeyOf(rv, st.depth); ok {
+		delete(st.ptrSeen, key)
+	}
+	st.depth--
+}
 
+// 获取需要检测循环引用的值的标识：仅在嵌套深度超过 SIFT_CYCLE_DETECT_DEPTH 之后检测非 nil 的指针/切片/映射
+// （与 json 标准库一样，避免在常见的浅层结构上付出额外的开销）。
+func cycleKeyOf(rv reflect.Value, depth int) (siftCycleKey, bool) {
+	if depth <= SIFT_CYCLE_DETECT_DEPTH {
+		return siftCycleKey{}, false
+	}
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return siftCycleKey{}, false
+		}
+		key := siftCycleKey{ptr: rv.Pointer()}
+		if rv.Kind() == reflect.Slice {
+			key.len = rv.Len()
+		}
+		return key, true
+	}
+	return siftCycleKey{}, false
+}
+
+// 对结构体（或者其指针）进行筛选。
+//
+// Note:
+//  传入指针时，域值是可寻址的，与 json 标准库一致，指针接收者实现的 json.Marshaler 等方法也会生效。
+func (sifter *Sifter) SiftStruct(s interface{}, maxConfidentialLevel int) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	return sifter.cs.siftStruct(rv, maxConfidentialLevel, &siftState{})
+}
+
+// 与 SiftStruct() 相同，但是深拷贝结果中直接输出的原值（切片、映射、指针等），结果不再与 s 共享可变的内存。
+func (sifter *Sifter) SiftStructCopy(s interface{}, maxConfidentialLevel int) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	return sifter.cs.siftStruct(rv, maxConfidentialLevel, &siftState{deepCopy: true})
+}
+
+func (cs *cachedSifter) siftStruct(rv reflect.Value, maxConfidentialLevel int, st *siftState) (map[string]interface{}, error) {
 	// fmt.Printf("cachedSifter[%s]\n", cs)
 
-	out := make(map[string]interface{}) // 最终的输出
-	for idx := 0; idx < len(siList); idx++ {
-		if idx > MAX_JSON_FIELD_NUMBER {
-			return nil, fmt.Errorf("abort due to too many json fields (limit %d)", MAX_JSON_FIELD_NUMBER)
+	items := cs.visibleItems(maxConfidentialLevel) // 已经按照安全级别筛选过的域
+
+	out := make(map[string]interface{}, len(items)) // 最终的输出
+	for _, si := range items {
+		v, ok, err := si.sift(rv, maxConfidentialLevel, st)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out[si.alias] = v
 		}
+	}
+	return out, nil
+}
+
+// 筛选结构体中的一个域；域被忽略（嵌入指针为 nil、omitempty/omitzero 等）时返回 false。
+func (si *sifterItem) sift(rv reflect.Value, maxConfidentialLevel int, st *siftState) (interface{}, bool, error) {
+	fv, ok := fieldByIndex(rv, si.index)
+	if !ok || !fv.IsValid() || (si.isOmitEmpty && isEmptyValue(fv)) || (si.isZero != nil && si.isZero(fv)) {
+		return nil, false, nil
+	}
 
-		// current reflect value, parents' in list, sifter item
-		curRv, curIn, curSi := siList[idx].rv, siList[idx].in, siList[idx].si
+	// fmt.Printf("si[%s]\n", si)
 
-		if !curRv.Field(curSi.index).IsValid() || (curSi.isOmitEmpty && isEmptyValue(curRv.Field(curSi.index))) {
-			continue
+	if si.value != nil {
+		// 处理需要递归筛选的域（嵌套结构体、指针等）
+		v, err := si.value.sift(fv, maxConfidentialLevel, st)
+		if err != nil {
+			return nil, false, err
 		}
+		return v, true, nil
+	} else if si.isString {
+		v, err := quoteValue(fv)
+		if err != nil {
+			return nil, false, err
+		}
+		return v, true, nil
+	} else if fv.CanInterface() {
+		if st.deepCopy {
+			return deepCopy(fv), true, nil
+		}
+		return fv.Interface(), true, nil
+	}
+	return nil, false, nil
+}
 
-		// 按照安全级别筛选域
-		if curSi.cLevel > maxConfidentialLevel {
-			continue
+// 按照索引路径获取域值；路径上的嵌入指针为 nil 时返回 false（与 json 标准库一致，忽略此域）。
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		if rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
 		}
+		rv = rv.Field(i)
+	}
+	return rv, true
+}
 
-		// fmt.Printf("curSi[%s]\n", curSi)
+// 按照筛选方式处理域值；nil 指针（任意一级）按照 encoding/json 的方式输出为 null。
+//
+// Note:
+//  嵌套深度超过 MAX_SIFT_DEPTH 时直接报错；超过 SIFT_CYCLE_DETECT_DEPTH 之后开始检测循环引用。
+func (vs *valueSifter) sift(rv reflect.Value, maxConfidentialLevel int, st *siftState) (interface{}, error) {
+	if err := st.enter(rv); err != nil {
+		return nil, err
+	}
+	defer st.leave(rv)
 
-		if curSi.embedded == nil {
-			// 处理非嵌入式结构体域的情况（按照 curIn 将值放在合适的节点上）
-			end := out
-			for _, parent := range curIn {
-				if _, exist := end[parent]; !exist {
-					end[parent] = make(map[string]interface{})
-				}
-				end = end[parent].(map[string]interface{})
+	switch vs.kind {
+	case vkPtr:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return vs.elem.sift(rv.Elem(), maxConfidentialLevel, st)
+	case vkSlice:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		out := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			v, err := vs.elem.sift(rv.Index(i), maxConfidentialLevel, st)
+			if err != nil {
+				return nil, err
 			}
-			end[curSi.alias] = curRv.Field(curSi.index).Interface()
-		} else {
-			// 处理嵌入式结构体的情况（将嵌入式域依次写FIFO等待处理）
-			if !curSi.isAnonymous || (curSi.alias != "") {
-				curIn = append(curIn, curSi.alias)
+			out[i] = v
+		}
+		return out, nil
+	case vkMap:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k, err := resolveKeyName(iter.Key())
+			if err != nil {
+				return nil, err
 			}
-
-			for _, si := range curSi.embedded.sifterItems {
-				siList = append(siList, sifterItemCtx{
-					rv: curRv.Field(curSi.index),
-					in: curIn,
-					si: si,
-				})
+			v, err := vs.elem.sift(iter.Value(), maxConfidentialLevel, st)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = v
+		}
+		return out, nil
+	case vkIface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		// 接口的动态类型只有在运行时才能确定
+		evs, err := getValueSifter(rv.Elem().Type())
+		if err != nil {
+			return nil, err
+		}
+		if evs == nil {
+			if st.deepCopy {
+				return deepCopy(rv.Elem()), nil
 			}
+			return rv.Elem().Interface(), nil
+		}
+		return evs.sift(rv.Elem(), maxConfidentialLevel, st)
+	case vkAddrMarshaler:
+		if rv.CanAddr() {
+			if st.deepCopy {
+				return deepCopyAddr(rv), nil
+			}
+			return rv.Addr().Interface(), nil
+		}
+		if vs.elem != nil {
+			// 无法调用指针接收者的方法时，依然需要筛选其内部的域
+			return vs.elem.sift(rv, maxConfidentialLevel, st)
 		}
+		if st.deepCopy {
+			return deepCopy(rv), nil
+		}
+		return rv.Interface(), nil
+	case vkStruct:
+		if st.ordered {
+			return vs.embedded.siftOrdered(rv, maxConfidentialLevel, st)
+		}
+		return vs.embedded.siftStruct(rv, maxConfidentialLevel, st)
+	default:
+		return nil, fmt.Errorf("unsupported value sifter kind %d", vs.kind)
 	}
-	return out, nil
 }
 
 func (cs *cachedSifter) String() string {
 	var slist []string
 
 	for _, si := range cs.sifterItems {
-		if si.embedded != nil {
-			// Note: 并非尾递归（golang 也不支持），注意调用栈嵌套问题；
-			slist = append(slist, fmt.Sprintf("index[%d], field[%s], isAnonymous[%v], embedded[%s]",
-				si.index, si.field, si.isAnonymous, si.embedded.String()))
-		} else {
-			slist = append(slist, fmt.Sprintf("index[%d], field[%s], alias[%s], isOmitEmpty[%v], cLevel[%d]",
-				si.index, si.field, si.alias, si.isOmitEmpty, si.cLevel))
-		}
+		slist = append(slist, si.String())
 	}
 
 	return strings.Join(slist, "; ")
 }
 
+func (vs *valueSifter) String() string {
+	switch vs.kind {
+	case vkPtr:
+		return "*" + vs.elem.String()
+	case vkSlice:
+		return "[]" + vs.elem.String()
+	case vkMap:
+		return "map[]" + vs.elem.String()
+	case vkIface:
+		return "interface{}"
+	case vkAddrMarshaler:
+		return "&marshaler"
+	case vkStruct:
+		// 不展开结构体（自引用类型的展开无法终止）
+		return "struct " + vs.embedded.typ.String()
+	default:
+		return "unknown"
+	}
+}
+
 func (si *sifterItem) String() string {
-	return fmt.Sprintf("index[%d], field[%s], alias[%s], isOmitEmpty[%v], cLevel[%d], isAnonymous[%v], hasEmbedded[%v]",
-		si.index, si.field, si.alias, si.isOmitEmpty, si.cLevel, si.isAnonymous, si.embedded != nil)
+	str := fmt.Sprintf("index%v, field[%s], alias[%s], isTagged[%v], isOmitEmpty[%v], isOmitZero[%v], isString[%v], cLevel[%d]",
+		si.index, si.field, si.alias, si.isTagged, si.isOmitEmpty, si.isZero != nil, si.isString, si.cLevel)
+	if si.value != nil {
+		str += fmt.Sprintf(", value[%s]", si.value.String())
+	}
+	return str
+}
+
+// 对任意值进行筛选：其中的结构体（包括嵌套在指针、切片、映射、接口中的结构体）被转换为 map[string]interface{}，
+// 切片/数组转换为 []interface{}，其余的值原样返回；nil（包括 nil 指针）返回 nil。
+func Sift(v interface{}, maxConfidentialLevel int) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	vs, err := cachedValueSifter(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	if vs == nil {
+		return v, nil
+	}
+	return vs.sift(rv, maxConfidentialLevel, &siftState{})
+}
+
+// 某一个结构体类型的 sifter（由 GetSifter() 获取，可以在多个 goroutine 中同时使用）
+type Sifter struct {
+	cs *cachedSifter
 }
 
 // 针对某一个具体的结构体类型获取缓存的 sifter；如果不存在则将尝试新建对应的 sifter。
-func GetSifter(rt reflect.Type) (cachedSifter, error) {
-	sifterCache.RLock()
-	cs, cached := sifterCache.m[rt]
-	sifterCache.RUnlock()
+func GetSifter(rt reflect.Type) (*Sifter, error) {
+	cs, err := getSifter(rt)
+	if err != nil {
+		return nil, err
+	}
+	return &Sifter{cs: cs}, nil
+}
+
+// 对应的结构体类型
+func (sifter *Sifter) Type() reflect.Type {
+	return sifter.cs.typ
+}
+
+// 某一个保密级别下可见的域的别名（即序列化之后的键），按照结构体域的声明顺序排列（嵌入结构体的域已展开）
+func (sifter *Sifter) Fields(maxConfidentialLevel int) []string {
+	items := sifter.cs.visibleItems(maxConfidentialLevel)
+	fields := make([]string, len(items))
+	for i, si := range items {
+		fields[i] = si.alias
+	}
+	return fields
+}
+
+func (sifter *Sifter) String() string {
+	return sifter.cs.String()
+}
 
-	if cached {
+func getSifter(rt reflect.Type) (*cachedSifter, error) {
+	if cs := lookupSifter(rt); cs != nil {
 		return cs, nil
 	}
+	if atomic.LoadInt32(&rejectUnregistered) == 1 {
+		return nil, &UnregisteredTypeError{Type: rt}
+	}
+	return buildSifter(rt)
+}
 
-	cs, err := generateSifter(rt)
+// 新建 sifter 并写入缓存
+func buildSifter(rt reflect.Type) (*cachedSifter, error) {
+	// 构建过程中所有新建的 sifter（包括嵌套引用的结构体类型），全部构建成功之后才写入缓存，
+	// 以免其他 goroutine 拿到尚未填充完整的占位 sifter
+	building := make(map[reflect.Type]*cachedSifter)
+	cs, err := generateSifter(rt, building)
 	if err != nil {
-		return cachedSifter{}, err
+		return nil, err
 	}
 
 	sifterCache.Lock()
-	if sifterCache.m == nil {
-		sifterCache.m = make(map[reflect.Type]cachedSifter)
+	old := loadSifterCache()
+	m := make(map[reflect.Type]*cachedSifter, len(old)+len(building))
+	for t, s := range old {
+		m[t] = s
 	}
-	sifterCache.m[rt] = cs
+	for t, s := range building {
+		if _, exist := m[t]; !exist {
+			m[t] = s
+		}
+	}
+	sifterCache.m.Store(m)
+	cs = m[rt]
 	sifterCache.Unlock()
 
 	return cs, nil
 }
 
+// 注册结构体类型：预先构建其 sifter（包括嵌套引用的结构体类型）以及各个保密级别下的 encoder，
+// 标签错误以及无法序列化的域类型（chan、func、complex、键类型不合法的映射等）在注册时即可发现。
+//
+// Note:
+//  注册不受 SetRejectUnregistered() 的限制。
+func Register(rt reflect.Type) error {
+	if rt.Kind() != reflect.Struct {
+		return fmt.Errorf("invalid param type %v", rt.Kind())
+	}
+	if lookupSifter(rt) == nil {
+		if _, err := buildSifter(rt); err != nil {
+			return err
+		}
+		// 此前可能因为类型尚未注册而缓存了报错的 encoder
+		resetEncoderCache()
+	}
+	if err := checkEncodable(rt, make(map[reflect.Type]bool)); err != nil {
+		return err
+	}
+	for level := CONFIDENTIAL_LEVEL0; level <= CONFIDENTIAL_LEVEL_MAX; level++ {
+		typeEncoder(rt, level)
+		typeEncoder(reflect.PtrTo(rt), level)
+	}
+	return nil
+}
+
+// 检查类型（以及静态引用的类型）是否可以被 json 序列化
+//
+// @param
+//  seen - 已经检查过的结构体类型（用于处理递归类型）
+func checkEncodable(rt reflect.Type, seen map[reflect.Type]bool) error {
+	if isMarshalerType(rt) || (rt.Kind() != reflect.Ptr && isMarshalerType(reflect.PtrTo(rt))) {
+		return nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return fmt.Errorf("json: unsupported type: %v", rt)
+	case reflect.Map:
+		if !isValidMapKeyType(rt.Key()) {
+			return fmt.Errorf("json: unsupported type: %v", rt)
+		}
+		return checkEncodable(rt.Elem(), seen)
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return checkEncodable(rt.Elem(), seen)
+	case reflect.Struct:
+		if seen[rt] {
+			return nil
+		}
+		seen[rt] = true
+
+		cs, err := getSifter(rt)
+		if err != nil {
+			return err
+		}
+		for _, si := range cs.sifterItems {
+			if err := checkEncodable(si.typ, seen); err != nil {
+				return fmt.Errorf("field %v.%s: %s", rt, si.field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// 设置是否拒绝为尚未注册（或者尚未缓存）的结构体类型新建 sifter；开启之后只能使用已经注册的类型，
+// 以及从这些类型（静态地）引用的结构体类型，接口的动态类型同样需要预先注册。
+//
+// Note:
+//  1. 开启之前已经使用过（即已经缓存了 sifter）的类型同样视为已经注册；
+//  2. 设置变化时清空 encoder 缓存，以免继续使用此前因为类型尚未注册而缓存的报错 encoder。
+func SetRejectUnregistered(reject bool) {
+	var flag int32
+	if reject {
+		flag = 1
+	}
+	if atomic.SwapInt32(&rejectUnregistered, flag) != flag {
+		resetEncoderCache()
+	}
+}
+
+// 开启 SetRejectUnregistered() 之后使用尚未注册的类型时返回的错误
+type UnregisteredTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnregisteredTypeError) Error() string {
+	return fmt.Sprintf("abort due to unregistered type %v", e.Type)
+}
+
+// 获取某一个类型的值的筛选方式（运行时用于接口的动态类型）
+func getValueSifter(rt reflect.Type) (*valueSifter, error) {
+	return generateValueSifter(rt, nil)
+}
+
 // 根据具体的结构体类型产生特定的 sifter。
-func generateSifter(rt reflect.Type) (cachedSifter, error) {
+//
+// @param
+//  rt - 结构体类型
+//  building - 本次构建过程中已经（或者正在）构建的 sifter
+//
+// Note:
+//  1. 已经缓存的 sifter 直接复用；自引用或者互相引用的结构体类型（如 `type Node struct { Children []Node; Parent *Node }`）
+//  在构建过程中会再次遇到正在构建的类型，此时直接引用其占位 sifter（与 json 标准库的做法类似），待构建完成后
+//  占位 sifter 即被填充完整；
+//  2. 无别名的匿名结构体域（包括指向结构体的指针，以及非公开的结构体类型）会被展开至当前层，同名域的处理规则
+//  与 json 标准库一致（参考 typeFields()）：嵌套层次较浅的域优先，同一层次中设置了 json 标签的域优先，仍然无法
+//  区分的同名域全部忽略。
+func generateSifter(rt reflect.Type, building map[reflect.Type]*cachedSifter) (*cachedSifter, error) {
+	if cs := lookupSifter(rt); cs != nil {
+		return cs, nil
+	}
+	if cs, exist := building[rt]; exist {
+		return cs, nil
+	}
+	// placeholder
+	cs := &cachedSifter{typ: rt}
+	building[rt] = cs
+
+	// 待展开的匿名结构体（当前层以及下一层）
+	type embeddedStruct struct {
+		typ    reflect.Type
+		index  []int
+		cLevel int
+	}
+	current := []embeddedStruct{}
+	next := []embeddedStruct{{typ: rt}}
+
+	// 当前层以及下一层中各个匿名结构体类型出现的次数
+	var count, nextCount map[reflect.Type]int
+
+	// 已经在较浅层次展开过的结构体类型
+	visited := map[reflect.Type]bool{}
+
 	sList := make([]*sifterItem, 0)
 
-	for i := 0; i < rt.NumField(); i++ {
-		si := &sifterItem{
-			index:       i,
-			field:       rt.Field(i).Name,
-			isAnonymous: rt.Field(i).Anonymous,
-			// below are default values
-			embedded:    nil,
-			alias:       "",
-			isOmitEmpty: false,
-			cLevel:      CONFIDENTIAL_LEVEL0,
-		}
-
-		// 处理 json 标签
-		if ignore, alias, omitempty, err := parseJsonTags(si.field, rt.Field(i).Tag.Get("json"), si.isAnonymous); err != nil {
-			return cachedSifter{}, err
-		} else if ignore {
-			continue
-		} else {
-			si.alias = alias
-			si.isOmitEmpty = omitempty
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, es := range current {
+			if visited[es.typ] {
+				continue
+			}
+			visited[es.typ] = true
+
+			for i := 0; i < es.typ.NumField(); i++ {
+				sf := es.typ.Field(i)
+
+				// 匿名域指向结构体的指针按照结构体处理
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				// go struct field visibility：非公开的匿名结构体域中可能存在公开的域，不能忽略
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				// 处理 json 标签
+				ignore, alias, omitempty, omitzero, asString := parseJsonTags(sf.Tag.Get("json"))
+				if ignore {
+					continue
+				}
+
+				// 处理保密/脱敏标签
+				clevel, err := parseConfidentialTags(sf.Tag.Get(TAG_CONFIDENTIAL))
+				if err != nil {
+					return nil, err
+				}
+				if clevel < es.cLevel {
+					clevel = es.cLevel
+				}
+
+				index := make([]int, len(es.index)+1)
+				copy(index, es.index)
+				index[len(es.index)] = i
+
+				// 无别名的匿名结构体域：留待下一层展开
+				if alias == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, embeddedStruct{typ: ft, index: index, cLevel: clevel})
+					}
+					continue
+				}
+
+				si := &sifterItem{
+					index:       index,
+					field:       sf.Name,
+					typ:         sf.Type,
+					alias:       alias,
+					isTagged:    alias != "",
+					isOmitEmpty: omitempty,
+					isString:    asString && isQuotableType(sf.Type),
+					cLevel:      clevel,
+				}
+				if si.alias == "" {
+					si.alias = sf.Name
+				}
+				if omitzero {
+					si.isZero = zeroChecker(sf.Type)
+				}
+				if ytag, ok := sf.Tag.Lookup("yaml"); ok {
+					si.yaml = parseYamlTags(ytag)
+				}
+
+				vs, err := generateValueSifter(sf.Type, building)
+				if err != nil {
+					return nil, err
+				}
+				si.value = vs
+
+				sList = append(sList, si)
+				if count[es.typ] > 1 {
+					// 同一层次中出现了多个相同的匿名结构体类型，再添加一次以确保下面的同名域处理逻辑将其全部忽略
+					sList = append(sList, si)
+				}
+			}
 		}
+	}
 
-		// 处理保密/脱敏标签
-		if clevel, err := parseConfidentialTags(rt.Field(i).Tag.Get(TAG_CONFIDENTIAL)); err != nil {
-			return cachedSifter{}, err
-		} else {
-			si.cLevel = clevel
+	sList = dominantSifterItems(sList)
+	if len(sList) > MAX_JSON_FIELD_NUMBER {
+		return nil, fmt.Errorf("abort due to too many json fields (limit %d)", MAX_JSON_FIELD_NUMBER)
+	}
+
+	cs.sifterItems = sList
+	for level := CONFIDENTIAL_LEVEL0; level <= CONFIDENTIAL_LEVEL_MAX; level++ {
+		for _, si := range sList {
+			if si.cLevel <= level {
+				cs.levelItems[level] = append(cs.levelItems[level], si)
+			}
 		}
+	}
+	return cs, nil
+}
 
-		// 处理嵌套的结构体
-		if rt.Field(i).Type.Kind() == reflect.Struct {
-			// embedded sifter
-			eSifter, err := generateSifter(rt.Field(i).Type)
-			if err != nil {
-				return cachedSifter{}, err
+// 处理同名的域（Go 嵌入结构体的规则，以及 json 标签的优先规则），并按照结构体域的声明顺序排列
+// @refer `/encoding/json/encode.go`
+func dominantSifterItems(sList []*sifterItem) []*sifterItem {
+	// 按照别名、嵌套层次、是否设置了 json 标签、索引路径排序
+	sort.Slice(sList, func(i, j int) bool {
+		x, y := sList[i], sList[j]
+		if x.alias != y.alias {
+			return x.alias < y.alias
+		}
+		if len(x.index) != len(y.index) {
+			return len(x.index) < len(y.index)
+		}
+		if x.isTagged != y.isTagged {
+			return x.isTagged
+		}
+		return indexLess(x.index, y.index)
+	})
+
+	out := sList[:0]
+	for advance, i := 0, 0; i < len(sList); i += advance {
+		// 每次处理一组同名的域（排在首位的即是优先的域）
+		si := sList[i]
+		for advance = 1; i+advance < len(sList); advance++ {
+			if sList[i+advance].alias != si.alias {
+				break
+			}
+		}
+		if advance > 1 {
+			sj := sList[i+1]
+			if len(si.index) == len(sj.index) && si.isTagged == sj.isTagged {
+				// 无法区分优先级，全部忽略
+				continue
 			}
-			si.embedded = &eSifter
 		}
+		out = append(out, si)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return indexLess(out[i].index, out[j].index)
+	})
+	return out
+}
 
-		sList = append(sList, si)
+// 按照 dominantSifterItems() 的规则处理其他格式（msgpack、yaml）使用自身标签重命名之后产生的同名键。
+//
+// @param
+//  indexes - 结构体的域的索引路径
+//  names - 各个域在该格式下的键名；空字符串表示该域不输出键（被忽略或者被展开）
+//  tagged - 各个域的键名是否来自标签
+// @return
+//  各个域是否保留
+func dominantKeys(indexes [][]int, names []string, tagged []bool) []bool {
+	keep := make([]bool, len(indexes))
+	groups := make(map[string][]int, len(indexes))
+	for i, name := range names {
+		if name != "" {
+			groups[name] = append(groups[name], i)
+		}
 	}
 
-	return cachedSifter{sifterItems: sList}, nil
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool {
+			x, y := group[i], group[j]
+			if len(indexes[x]) != len(indexes[y]) {
+				return len(indexes[x]) < len(indexes[y])
+			}
+			if tagged[x] != tagged[y] {
+				return tagged[x]
+			}
+			return indexLess(indexes[x], indexes[y])
+		})
+		if len(group) > 1 {
+			x, y := group[0], group[1]
+			if len(indexes[x]) == len(indexes[y]) && tagged[x] == tagged[y] {
+				// 无法区分优先级，全部忽略
+				continue
+			}
+		}
+		keep[group[0]] = true
+	}
+	return keep
 }
 
-// 可解析如下类型的 json 标签：
+// 其他格式（如 msgpack）按照自身的标签选择的结构体域（参考 formatFields()）
+type formatField struct {
+	index  []int               // 索引路径（与 sifterItem.index 相同）
+	sf     reflect.StructField // 域本身（即 typ.FieldByIndex(index)）
+	name   string              // 键名：标签设置的别名，其次为域名
+	tagged bool                // 键名是否来自标签
+	cLevel int                 // 保密级别；嵌入结构体的域取索引路径上的最高保密级别
+}
+
+// 按照 generateSifter() 的规则（嵌入结构体的展开、同名域的处理）获取结构体在其他格式下输出的域（按照声明顺序排列），
+// 但是域的忽略以及别名由该格式自身的标签决定（如 `json:"-"` 且设置了 `msgpack:"name"` 的域依然输出）。
+//
+// @param
+//  tagFunc - 解析结构体域的标签：ignore 表示忽略此域，alias 为标签设置的别名（没有设置时为空，此时嵌入结构体被展开）
+func formatFields(rt reflect.Type, tagFunc func(sf reflect.StructField) (alias string, ignore bool)) ([]*formatField, error) {
+	type embeddedStruct struct {
+		typ    reflect.Type
+		index  []int
+		cLevel int
+	}
+	var current []embeddedStruct
+	next := []embeddedStruct{{typ: rt}}
+	var count, nextCount map[reflect.Type]int
+	visited := map[reflect.Type]bool{}
+
+	var fields []*formatField
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, es := range current {
+			if visited[es.typ] {
+				continue
+			}
+			visited[es.typ] = true
+
+			for i := 0; i < es.typ.NumField(); i++ {
+				sf := es.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				alias, ignore := tagFunc(sf)
+				if ignore {
+					continue
+				}
+				clevel, err := parseConfidentialTags(sf.Tag.Get(TAG_CONFIDENTIAL))
+				if err != nil {
+					return nil, err
+				}
+				if clevel < es.cLevel {
+					clevel = es.cLevel
+				}
+
+				index := make([]int, len(es.index)+1)
+				copy(index, es.index)
+				index[len(es.index)] = i
+
+				if alias == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, embeddedStruct{typ: ft, index: index, cLevel: clevel})
+					}
+					continue
+				}
+
+				f := &formatField{index: index, sf: sf, name: alias, tagged: alias != "", cLevel: clevel}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				fields = append(fields, f)
+				if count[es.typ] > 1 {
+					// 与 generateSifter() 相同：同一层次中出现了多个相同的匿名结构体类型时全部忽略
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+
+	indexes := make([][]int, len(fields))
+	names := make([]string, len(fields))
+	tagged := make([]bool, len(fields))
+	for i, f := range fields {
+		indexes[i], names[i], tagged[i] = f.index, f.name, f.tagged
+	}
+	out := fields[:0]
+	for i, keep := range dominantKeys(indexes, names, tagged) {
+		if keep {
+			out = append(out, fields[i])
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return indexLess(out[i].index, out[j].index)
+	})
+	return out, nil
+}
+
+// 比较两个索引路径的先后顺序
+func indexLess(x, y []int) bool {
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return len(x) < len(y)
+}
+
+// 根据域值的类型产生对应的筛选方式；如果该类型的值不需要筛选（直接输出原值即可）则返回 nil。
+//
+// @param
+//  rt - 域值的类型
+//  building - 本次构建过程中已经（或者正在）构建的 sifter；nil 表示直接通过 getSifter() 获取结构体的 sifter
+//
+// Note:
+//  1. 指针（包括多级指针）会被依次解引用，最终指向结构体时才需要筛选；
+//  2. 切片/数组的元素需要筛选时，逐个元素进行筛选，输出为 []interface{}；
+//  3. 映射的值需要筛选时，逐个值进行筛选，输出为 map[string]interface{}（键的处理方式与 json 标准库一致）；
+//  4. 接口类型的值总是需要在运行时根据其动态类型进行筛选；
+//  5. 实现了 json.Marshaler/encoding.TextMarshaler 的类型（如 time.Time）视为不可再分的值，按照其自身的
+//     序列化方式输出，不再展开其内部的域（但其所在的域本身依然受保密级别的限制）。
+func generateValueSifter(rt reflect.Type, building map[reflect.Type]*cachedSifter) (*valueSifter, error) {
+	if isMarshalerType(rt) {
+		return nil, nil
+	}
+	if rt.Kind() != reflect.Ptr && isMarshalerType(reflect.PtrTo(rt)) {
+		elem, err := generateKindSifter(rt, building)
+		if err != nil {
+			return nil, err
+		}
+		return &valueSifter{kind: vkAddrMarshaler, elem: elem}, nil
+	}
+	return generateKindSifter(rt, building)
+}
+
+// 根据类型的 Kind 产生对应的筛选方式（不考虑类型自定义的序列化方式）
+func generateKindSifter(rt reflect.Type, building map[reflect.Type]*cachedSifter) (*valueSifter, error) {
+	switch rt.Kind() {
+	case reflect.Ptr:
+		elem, err := generateValueSifter(rt.Elem(), building)
+		if err != nil || elem == nil {
+			return nil, err
+		}
+		return &valueSifter{kind: vkPtr, elem: elem}, nil
+	case reflect.Slice, reflect.Array:
+		elem, err := generateValueSifter(rt.Elem(), building)
+		if err != nil || elem == nil {
+			return nil, err
+		}
+		return &valueSifter{kind: vkSlice, elem: elem}, nil
+	case reflect.Map:
+		elem, err := generateValueSifter(rt.Elem(), building)
+		if err != nil || elem == nil {
+			return nil, err
+		}
+		if !isValidMapKeyType(rt.Key()) {
+			return nil, fmt.Errorf("unsupported map key type %v", rt.Key())
+		}
+		return &valueSifter{kind: vkMap, elem: elem}, nil
+	case reflect.Interface:
+		return &valueSifter{kind: vkIface}, nil
+	case reflect.Struct:
+		var eSifter *cachedSifter
+		var err error
+		if building == nil {
+			eSifter, err = getSifter(rt)
+		} else {
+			eSifter, err = generateSifter(rt, building)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &valueSifter{kind: vkStruct, embedded: eSifter}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// 仅从缓存中查找 sifter
+func lookupSifter(rt reflect.Type) *cachedSifter {
+	return loadSifterCache()[rt]
+}
+
+func loadSifterCache() map[reflect.Type]*cachedSifter {
+	m, _ := sifterCache.m.Load().(map[reflect.Type]*cachedSifter)
+	return m
+}
+
+// 解析 json 标签，语法与 json 标准库一致：
 //
 // 1. 没有标签
-// 3. `json:"-"`
-// 2. `json:"json_alias"`
-// 4. `json:"json_alias,omitempty"`
-// 4. `json:",omitempty"`
+// 2. `json:"-"`
+// 3. `json:"-,"`（别名为 "-"）
+// 4. `json:"json_alias"`
+// 5. `json:"json_alias,opt1,opt2..."` 或者 `json:",opt1,opt2..."`
+//
+// 选项的顺序任意，支持 omitempty/omitzero/string，其余的选项被忽略（与 json 标准库一致）。
 //
 // @param
-//  name - 结构体成员名称
 //  jtag - json 标签字符串
-//  isAnonymous - 是否是匿名域
 // @return
-//  ignore  - 是否在序列化过程中忽略此项成员（如：明确指定 `json:"-"` 或者首字母小写的非公开成员）
-//  alias - 序列化时采用的别名，可能是 json 标签设置的别名，也可能是结构体域名称
+//  ignore  - 是否在序列化过程中忽略此项成员（明确指定 `json:"-"`；结构体域的可见性由调用者判断）
+//  alias - json 标签设置的别名；没有设置时为空（序列化时采用结构体域名称，或者展开匿名结构体域）
 //  omitempty - 当值为空时是否进行序列化
+//  omitzero - 当值为零值时是否进行序列化（Go 1.24 引入）
+//  asString - 是否以字符串的形式序列化（`string` 选项）
 //
 // Note:
 //  1. 值为空的判定参考 json 标准库的文档：
 //  false, 0, nil pointer or interface value, and any array, slice, map, or string of length zero
-//  2. 仅支持匿名域类型是结构体的场景。
-func parseJsonTags(name, jtag string, isAnonymous bool) (ignore bool, alias string, omitempty bool, err error) {
-	// go struct field visibility
-	if len(name) == 0 || unicode.IsLower(rune(name[0])) {
+//  2. 非法的别名（参考 json 标准库的 isValidTag()）按照没有设置别名处理。
+func parseJsonTags(jtag string) (ignore bool, alias string, omitempty, omitzero, asString bool) {
+	if jtag == "-" {
+		// `json:"-"`
 		ignore = true
 		return
 	}
 
 	jtags := strings.Split(jtag, ",")
-	switch len(jtags) {
-	case 1:
-		t := strings.TrimSpace(jtags[0])
-		if t == "-" {
-			// `json:"-"`
-			ignore = true
-			return
-		} else if len(t) > 0 {
-			// `json:"json_alias"`
-			alias = t
-			return
-		} else {
-			// 没有设置 json 标签
-			if !isAnonymous {
-				alias = name
-			}
-			return
+	if t := jtags[0]; isValidTag(t) {
+		// `json:"json_alias"`
+		alias = t
+	}
+
+	for _, opt := range jtags[1:] {
+		switch opt {
+		case "omitempty":
+			omitempty = true
+		case "omitzero":
+			omitzero = true
+		case "string":
+			asString = true
 		}
-	case 2:
-		t0, t1 := strings.TrimSpace(jtags[0]), strings.TrimSpace(jtags[1])
-		if t1 != "omitempty" {
-			ignore = true
-			err = fmt.Errorf("json tag %s not supported", t1)
-			return
+	}
+	return
+}
+
+// yaml 标签中的别名以及选项
+type yamlTag struct {
+	ignore    bool   // `yaml:"-"`
+	alias     string // 为空时使用 json 标签中的别名
+	omitEmpty bool
+	inline    bool // 将结构体（或者映射）的域展开至当前层
+	flow      bool // 按照 flow 风格输出（`{a: 1}`、`[1, 2]`）
+}
+
+func parseYamlTags(ytag string) *yamlTag {
+	if ytag == "-" {
+		return &yamlTag{ignore: true}
+	}
+
+	yt := &yamlTag{}
+	ytags := strings.Split(ytag, ",")
+	yt.alias = ytags[0]
+	for _, opt := range ytags[1:] {
+		switch opt {
+		case "omitempty":
+			yt.omitEmpty = true
+		case "inline":
+			yt.inline = true
+		case "flow":
+			yt.flow = true
 		}
-		if t0 == "" {
-			// `json:",omitempty"`
-			if !isAnonymous {
-				alias = name
-			}
-			omitempty = true
-			return
-		} else {
-			// `json:"json_alias,omitempty"`
-			alias = t0
-			omitempty = true
-			return
+	}
+	return yt
+}
+
+// 判断 json 标签中的别名是否合法
+// @refer `/encoding/json/encode.go`
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but
+			// otherwise any punctuation chars are allowed
+			// in a tag name.
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
 		}
-	default:
-		// unsupported
-		ignore = true
-		err = fmt.Errorf("unsupported json tag string %s", jtag)
-		return
 	}
+	return true
 }
 
 // 解析保密/脱敏相关的标签
@@ -324,3 +1109,125 @@ func isEmptyValue(v reflect.Value) bool {
 	}
 	return false
 }
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// 判断某个类型是否自定义了序列化方式（json.Marshaler 或者 encoding.TextMarshaler）
+func isMarshalerType(rt reflect.Type) bool {
+	return rt.Implements(jsonMarshalerType) || rt.Implements(textMarshalerType)
+}
+
+// 判断映射的键类型是否可以被序列化（字符串、整数或者实现了 encoding.TextMarshaler）
+// @refer `/encoding/json/encode.go`
+func isValidMapKeyType(kt reflect.Type) bool {
+	switch kt.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return kt.Implements(textMarshalerType)
+}
+
+// 获取映射的键序列化之后的字符串
+// @refer `/encoding/json/encode.go`
+func resolveKeyName(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		buf, err := tm.MarshalText()
+		return string(buf), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unexpected map key type %v", k.Type())
+}
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// 根据域的类型产生 omitzero 选项的零值判定方法（优先使用类型自身的 IsZero() 方法）
+// @refer `/encoding/json/encode.go`
+func zeroChecker(t reflect.Type) func(reflect.Value) bool {
+	switch {
+	case t.Kind() == reflect.Interface && t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			// Avoid panics calling IsZero on a nil interface or
+			// non-nil interface with nil pointer.
+			return v.IsNil() ||
+				(v.Elem().Kind() == reflect.Ptr && v.Elem().IsNil()) ||
+				v.Interface().(isZeroer).IsZero()
+		}
+	case t.Kind() == reflect.Ptr && t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			// Avoid panics calling IsZero on nil pointer.
+			return v.IsNil() || v.Interface().(isZeroer).IsZero()
+		}
+	case t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			return v.Interface().(isZeroer).IsZero()
+		}
+	case reflect.PtrTo(t).Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			if !v.CanAddr() {
+				// Temporarily box v so we can take the address.
+				v2 := reflect.New(v.Type()).Elem()
+				v2.Set(v)
+				v = v2
+			}
+			return v.Addr().Interface().(isZeroer).IsZero()
+		}
+	default:
+		return reflect.Value.IsZero
+	}
+}
+
+// 判断 `string` 选项对某个类型是否生效（仅限字符串、浮点数、整数以及布尔类型，或者指向这些类型的指针）
+// @refer `/encoding/json/encode.go`
+func isQuotableType(t reflect.Type) bool {
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isMarshalerType(t) || isMarshalerType(reflect.PtrTo(t)) {
+		// 自定义序列化方式的类型不受 `string` 选项的影响
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+// 按照 `string` 选项将值转换为其 json 序列化结果的字符串形式；nil 指针依然输出为 null。
+func quoteValue(v reflect.Value) (interface{}, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,702 @@
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 按照保密级别输出 XML。
+//
+// 结构体的域只按照 xml 标签选择以及命名（与 json 标签无关），规则与 encoding/xml 一致，保密级别高于 maxConfidentialLevel
+// 的子元素以及属性均被筛除：
+//  1. `xml:"name"` 设置元素名，`xml:"ns name"` 同时设置命名空间（输出为 xmlns 属性），`xml:"a>b>c"` 将元素嵌套在
+//  <a><b> 中（相邻的域共享相同的父元素）；匿名结构体域总是被展开，同名的域较浅者优先，深度相同时返回 *xml.TagPathError；
+//  2. `xml:"name,attr"` 输出为属性，`xml:",chardata"`/`xml:",cdata"` 输出为文本，`xml:",innerxml"` 原样输出，
+//  `xml:",comment"` 输出为注释，`xml:",omitempty"` 忽略空值，`xml:"-"` 表示忽略；
+//  3. 元素名的优先级：XMLName 域的标签、XMLName 域的值、域的元素名（域的标签，其次为域的类型中 XMLName 的标签，
+//  再次为域名）、结构体的类型名；域的标签与其类型中 XMLName 的标签不一致时返回错误。
+//
+// 与 encoding/xml 的差异：带命名空间的属性（`xml:"ns name,attr"`）以及 xml.Name 类型的属性均返回错误。
+//
+// 其余类型：切片（[]byte 除外）输出为多个同名的元素，nil 指针/接口不输出，time.Time 输出为 RFC3339 时间戳，
+// 实现了 xml.Marshaler 或者 encoding.TextMarshaler 的类型按照其方法输出；映射等无法输出的类型返回错误。
+
+var (
+	xmlMarshalerType     = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+	xmlMarshalerAttrType = reflect.TypeOf((*xml.MarshalerAttr)(nil)).Elem()
+)
+
+// xml 标签中的选项
+const (
+	xmlElement = iota
+	xmlAttr
+	xmlCharData
+	xmlCData
+	xmlInnerXML
+	xmlComment
+)
+
+// 结构体中的域（XML 输出时的名称以及选项）
+type xmlField struct {
+	index     []int
+	name      string
+	xmlns     string   // `xml:"namespace name"` 中的命名空间
+	parents   []string // `a>b>c` 中的 a、b
+	mode      int
+	omitEmpty bool
+	cLevel    int // 保密级别；嵌入结构体的域取索引路径上的最高保密级别
+}
+
+// 结构体类型的 XML 输出计划：域的选择、命名以及同名域的处理均按照 encoding/xml 的规则（与 json 标签无关）
+type xmlPlan struct {
+	xmlname     *xmlField                               // XMLName 域（包括嵌入结构体中的 XMLName 域）；nil 表示没有
+	levelFields [CONFIDENTIAL_LEVEL_MAX + 1][]*xmlField // 各个保密级别下可见的域
+}
+
+var xmlPlanCache sync.Map // map[reflect.Type]*xmlPlan
+
+type xmlState struct {
+	siftState
+	bytes.Buffer
+}
+
+// api function
+//
+// 按照保密级别对任意值进行 XML 序列化；nil（包括 nil 指针）输出为空。
+//
+// @param
+//
+//	v - 需要执行筛选/脱敏的值（结构体、指针、切片等均可）
+//	maxConfidentialLevel - 最高允许的安全等级（高于此等级的将被筛除）
+func MarshalXMLLevel(v interface{}, maxConfidentialLevel int) ([]byte, error) {
+	e := &xmlState{}
+	if v != nil {
+		if err := e.marshalValue(reflect.ValueOf(v), xml.Name{}, maxConfidentialLevel); err != nil {
+			return nil, err
+		}
+	}
+	return e.Bytes(), nil
+}
+
+func getXmlPlan(t reflect.Type) (*xmlPlan, error) {
+	if plan, ok := xmlPlanCache.Load(t); ok {
+		return plan.(*xmlPlan), nil
+	}
+
+	xmlname, fields, err := xmlTypeFields(t, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	plan := &xmlPlan{xmlname: xmlname}
+	for _, f := range fields {
+		for level := f.cLevel; level <= CONFIDENTIAL_LEVEL_MAX; level++ {
+			plan.levelFields[level] = append(plan.levelFields[level], f)
+		}
+	}
+	xmlPlanCache.Store(t, plan)
+	return plan, nil
+}
+
+// 获取结构体类型的 XMLName 域以及输出的域（按照声明顺序排列）
+// @refer `/encoding/xml/typeinfo.go` getTypeInfo()
+//
+// @param
+//
+//	visiting - 正在展开的嵌入结构体类型（用于处理嵌入自身指针的类型）
+func xmlTypeFields(t reflect.Type, visiting map[reflect.Type]bool) (xmlname *xmlField, fields []*xmlField, err error) {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if (!sf.IsExported() && !sf.Anonymous) || sf.Tag.Get("xml") == "-" {
+			continue
+		}
+		clevel, err := parseConfidentialTags(sf.Tag.Get(TAG_CONFIDENTIAL))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// 嵌入结构体（指针）的域总是展开至当前层（与其 xml 标签无关）
+		if sf.Anonymous {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if visiting[ft] {
+					continue
+				}
+				innerName, inner, err := xmlTypeFields(ft, visiting)
+				if err != nil {
+					return nil, nil, err
+				}
+				if xmlname == nil && innerName != nil {
+					xmlname = innerName.embeddedIn(i, clevel)
+				}
+				for _, f := range inner {
+					if err := addXmlField(t, &fields, f.embeddedIn(i, clevel)); err != nil {
+						return nil, nil, err
+					}
+				}
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+		}
+
+		f, err := parseXmlField(t, sf)
+		if err != nil {
+			return nil, nil, err
+		}
+		f.index, f.cLevel = []int{i}, clevel
+		if sf.Name == "XMLName" {
+			xmlname = f
+			continue
+		}
+		if err := addXmlField(t, &fields, f); err != nil {
+			return nil, nil, err
+		}
+	}
+	return xmlname, fields, nil
+}
+
+// 嵌入结构体（位于外层结构体的第 i 个域）中的域在外层结构体中的副本
+func (f *xmlField) embeddedIn(i int, clevel int) *xmlField {
+	nf := *f
+	nf.index = append([]int{i}, f.index...)
+	if nf.cLevel < clevel {
+		nf.cLevel = clevel
+	}
+	return &nf
+}
+
+// 解析结构体域的 xml 标签
+// @refer `/encoding/xml/typeinfo.go` structFieldInfo()
+func parseXmlField(t reflect.Type, sf reflect.StructField) (*xmlField, error) {
+	f := &xmlField{}
+	tag := sf.Tag.Get("xml")
+	if i := strings.Index(tag, " "); i >= 0 {
+		f.xmlns, tag = tag[:i], tag[i+1:]
+	}
+
+	tokens := strings.Split(tag, ",")
+	if len(tokens) > 1 {
+		tag = tokens[0]
+		modes := 0
+		for _, opt := range tokens[1:] {
+			switch opt {
+			case "attr":
+				f.mode, modes = xmlAttr, modes+1
+			case "chardata":
+				f.mode, modes = xmlCharData, modes+1
+			case "cdata":
+				f.mode, modes = xmlCData, modes+1
+			case "innerxml":
+				f.mode, modes = xmlInnerXML, modes+1
+			case "comment":
+				f.mode, modes = xmlComment, modes+1
+			case "any":
+				// 输出时与普通的子元素相同
+			case "omitempty":
+				f.omitEmpty = true
+			}
+		}
+
+		valid := modes <= 1
+		if f.mode != xmlElement && f.mode != xmlAttr && (sf.Name == "XMLName" || tag != "") {
+			valid = false
+		}
+		if f.omitEmpty && f.mode != xmlElement && f.mode != xmlAttr {
+			valid = false
+		}
+		if !valid {
+			return nil, fmt.Errorf("xml: invalid tag in field %s of type %v: %q", sf.Name, t, sf.Tag.Get("xml"))
+		}
+	}
+
+	if f.xmlns != "" && tag == "" {
+		return nil, fmt.Errorf("xml: namespace without name in field %s of type %v: %q", sf.Name, t, sf.Tag.Get("xml"))
+	}
+	if f.xmlns != "" && f.mode == xmlAttr {
+		// encoding/xml 为属性的命名空间生成前缀（xmlns:prefix），暂不支持
+		return nil, fmt.Errorf("xml: namespace in attribute field %s of type %v is not supported", sf.Name, t)
+	}
+	if sf.Name == "XMLName" {
+		f.name = tag
+		return f, nil
+	}
+
+	if tag == "" {
+		// 元素名为域的类型中 XMLName 域的标签，其次为域名
+		if xn := lookupXMLName(sf.Type); xn != nil {
+			f.xmlns, f.name = xn.xmlns, xn.name
+		} else {
+			f.name = sf.Name
+		}
+		return f, nil
+	}
+
+	parents := strings.Split(tag, ">")
+	if parents[0] == "" {
+		parents[0] = sf.Name
+	}
+	if parents[len(parents)-1] == "" {
+		return nil, fmt.Errorf("xml: trailing '>' in field %s of type %v", sf.Name, t)
+	}
+	f.name = parents[len(parents)-1]
+	if len(parents) > 1 {
+		if f.mode != xmlElement {
+			return nil, fmt.Errorf("xml: %s chain not valid with %s flag", tag, strings.Join(tokens[1:], ","))
+		}
+		f.parents = parents[:len(parents)-1]
+	}
+
+	// 域的类型中设置了 XMLName 的标签时，二者必须一致
+	if f.mode == xmlElement {
+		if xn := lookupXMLName(sf.Type); xn != nil && xn.name != f.name {
+			return nil, fmt.Errorf("xml: name %q in tag of %v.%s conflicts with name %q in %v.XMLName", f.name, t, sf.Name, xn.name, sf.Type)
+		}
+	}
+	return f, nil
+}
+
+// 类型（或者其指向的类型）中 XMLName 域的标签；没有设置元素名时返回 nil
+// @refer `/encoding/xml/typeinfo.go` lookupXMLName()
+func lookupXMLName(t reflect.Type) *xmlField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Name != "XMLName" {
+			continue
+		}
+		if f, err := parseXmlField(t, sf); err == nil && f.name != "" {
+			return f
+		}
+		break
+	}
+	return nil
+}
+
+// 添加一个域并处理同名的域：层次较浅的优先，同一层次中的同名域返回错误
+// @refer `/encoding/xml/typeinfo.go` addFieldInfo()
+func addXmlField(t reflect.Type, fields *[]*xmlField, newf *xmlField) error {
+	var conflicts []int
+Loop:
+	for i, oldf := range *fields {
+		if oldf.mode != newf.mode {
+			continue
+		}
+		if oldf.xmlns != "" && newf.xmlns != "" && oldf.xmlns != newf.xmlns {
+			continue
+		}
+		minl := len(newf.parents)
+		if len(oldf.parents) < minl {
+			minl = len(oldf.parents)
+		}
+		for p := 0; p < minl; p++ {
+			if oldf.parents[p] != newf.parents[p] {
+				continue Loop
+			}
+		}
+		switch {
+		case len(oldf.parents) > len(newf.parents):
+			if oldf.parents[len(newf.parents)] == newf.name {
+				conflicts = append(conflicts, i)
+			}
+		case len(oldf.parents) < len(newf.parents):
+			if newf.parents[len(oldf.parents)] == oldf.name {
+				conflicts = append(conflicts, i)
+			}
+		default:
+			if newf.name == oldf.name && newf.xmlns == oldf.xmlns {
+				conflicts = append(conflicts, i)
+			}
+		}
+	}
+	if conflicts == nil {
+		*fields = append(*fields, newf)
+		return nil
+	}
+
+	for _, i := range conflicts {
+		if len((*fields)[i].index) < len(newf.index) {
+			return nil
+		}
+	}
+	for _, i := range conflicts {
+		if oldf := (*fields)[i]; len(oldf.index) == len(newf.index) {
+			f1, f2 := t.FieldByIndex(oldf.index), t.FieldByIndex(newf.index)
+			return &xml.TagPathError{Struct: t, Field1: f1.Name, Tag1: f1.Tag.Get("xml"), Field2: f2.Name, Tag2: f2.Tag.Get("xml")}
+		}
+	}
+	for c := len(conflicts) - 1; c >= 0; c-- {
+		i := conflicts[c]
+		*fields = append((*fields)[:i], (*fields)[i+1:]...)
+	}
+	*fields = append(*fields, newf)
+	return nil
+}
+
+// 类型名（泛型类型去掉类型参数）
+func xmlTypeName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// 输出一个值对应的元素（切片输出为多个元素）
+//
+// @param
+//
+//	name - 域的元素名；为空时按照值的类型确定（结构体的 XMLName 域优先于域的元素名，与 encoding/xml 一致）
+func (e *xmlState) marshalValue(rv reflect.Value, name xml.Name, level int) error {
+	// nil 指针/接口不输出
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		if rv.Kind() == reflect.Interface {
+			rv = rv.Elem()
+			continue
+		}
+		if rv.Type().Implements(xmlMarshalerType) || rv.Type().Implements(textMarshalerType) {
+			break
+		}
+		if err := e.enter(rv); err != nil {
+			return err
+		}
+		defer e.leave(rv)
+		rv = rv.Elem()
+	}
+
+	t := rv.Type()
+	if rv.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// 自定义的序列化方法（指针接收者的方法只在值可寻址时生效）
+	if m, ok := xmlMarshalerOf(rv); ok {
+		if name.Local == "" {
+			name = xml.Name{Local: xmlTypeName(t)}
+		}
+		return e.callMarshalXML(m, name)
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < rv.Len(); i++ {
+				if err := e.marshalValue(rv.Index(i), name, level); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Struct:
+		if !isTextValue(rv) {
+			return e.marshalStruct(rv, name, level)
+		}
+	}
+
+	text, err := xmlText(rv)
+	if err != nil {
+		return err
+	}
+	if name.Local == "" {
+		name = xml.Name{Local: xmlTypeName(t)}
+	}
+	if name.Local == "" {
+		return fmt.Errorf("xml: unsupported type: %v", rv.Type())
+	}
+	e.writeStart(name, nil)
+	xml.EscapeText(e, []byte(text))
+	e.writeEnd(name.Local)
+	return nil
+}
+
+func xmlMarshalerOf(rv reflect.Value) (xml.Marshaler, bool) {
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() && reflect.PtrTo(rv.Type()).Implements(xmlMarshalerType) {
+		return rv.Addr().Interface().(xml.Marshaler), true
+	}
+	if rv.Type().Implements(xmlMarshalerType) {
+		return rv.Interface().(xml.Marshaler), true
+	}
+	return nil, false
+}
+
+func (e *xmlState) callMarshalXML(m xml.Marshaler, name xml.Name) error {
+	enc := xml.NewEncoder(e)
+	if err := m.MarshalXML(enc, xml.StartElement{Name: name}); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// 是否按照文本输出（time.Time 以及实现了 encoding.TextMarshaler 的结构体）
+func isTextValue(rv reflect.Value) bool {
+	t := rv.Type()
+	return t == timeType || t.Implements(textMarshalerType) || (rv.CanAddr() && reflect.PtrTo(t).Implements(textMarshalerType))
+}
+
+// 标量的文本形式
+func xmlText(rv reflect.Value) (string, error) {
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return "", nil
+	}
+	if rv.Type() == timeType {
+		return rv.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	}
+	var tm encoding.TextMarshaler
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() && reflect.PtrTo(rv.Type()).Implements(textMarshalerType) {
+		tm = rv.Addr().Interface().(encoding.TextMarshaler)
+	} else if rv.Type().Implements(textMarshalerType) {
+		tm = rv.Interface().(encoding.TextMarshaler)
+	}
+	if tm != nil {
+		b, err := tm.MarshalText()
+		if err != nil {
+			return "", fmt.Errorf("xml: error calling MarshalText for type %v: %v", rv.Type(), err)
+		}
+		return string(b), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return string(b), nil
+		}
+	}
+	return "", fmt.Errorf("xml: unsupported type: %v", rv.Type())
+}
+
+func (e *xmlState) marshalStruct(rv reflect.Value, name xml.Name, level int) error {
+	// 保密级别标签的校验以及未注册类型的处理与其他格式一致
+	if _, err := getSifter(rv.Type()); err != nil {
+		return err
+	}
+	plan, err := getXmlPlan(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	// 元素名：XMLName 域的标签，其次为 XMLName 域的值，再次为域的元素名，最后为类型名
+	if xn := plan.xmlname; xn != nil {
+		if xn.name != "" {
+			name = xml.Name{Space: xn.xmlns, Local: xn.name}
+		} else if fv, ok := fieldByIndex(rv, xn.index); ok && fv.CanInterface() {
+			if v, ok := fv.Interface().(xml.Name); ok && v.Local != "" {
+				name = v
+			}
+		}
+	}
+	if name.Local == "" {
+		name = xml.Name{Local: xmlTypeName(rv.Type())}
+	}
+	if name.Local == "" {
+		return fmt.Errorf("xml: start tag with no name for type %v", rv.Type())
+	}
+
+	var fields []*xmlField
+	if level >= CONFIDENTIAL_LEVEL0 {
+		if level > CONFIDENTIAL_LEVEL_MAX {
+			level = CONFIDENTIAL_LEVEL_MAX
+		}
+		fields = plan.levelFields[level]
+	}
+
+	// 需要输出的域值（nil 指针、omitempty 等被忽略的域为无效值）；
+	// 位于 nil 匿名结构体指针中的域不可达，其余被忽略的域依然会关闭不同的父元素（与 encoding/xml 一致）
+	values := make([]reflect.Value, len(fields))
+	reachable := make([]bool, len(fields))
+	var attrs []string
+	for i, f := range fields {
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok || !fv.IsValid() || !fv.CanInterface() {
+			continue
+		}
+		reachable[i] = true
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+		if f.mode != xmlAttr {
+			values[i] = fv
+			continue
+		}
+
+		value, err := xmlAttrValue(fv, f.name)
+		if err != nil {
+			return err
+		}
+		attrs = append(attrs, f.name, value)
+	}
+
+	e.writeStart(name, attrs)
+
+	var parents []string // 当前已经打开的父元素
+	for i, f := range fields {
+		if !reachable[i] || f.mode == xmlAttr {
+			continue
+		}
+
+		// 关闭与当前域不同的父元素（innerxml 除外），输出时再打开缺少的父元素
+		common := len(parents)
+		if f.mode != xmlInnerXML {
+			common = 0
+			for common < len(parents) && common < len(f.parents) && parents[common] == f.parents[common] {
+				common++
+			}
+			for j := len(parents) - 1; j >= common; j-- {
+				e.writeEnd(parents[j])
+			}
+			parents = parents[:common]
+		}
+
+		fv := values[i]
+		if !fv.IsValid() {
+			continue
+		}
+		if f.mode == xmlElement {
+			for _, p := range f.parents[common:] {
+				e.writeStart(xml.Name{Local: p}, nil)
+				parents = append(parents, p)
+			}
+		}
+
+		if err := e.marshalField(f, fv, level); err != nil {
+			return err
+		}
+	}
+	for j := len(parents) - 1; j >= 0; j-- {
+		e.writeEnd(parents[j])
+	}
+
+	e.writeEnd(name.Local)
+	return nil
+}
+
+func (e *xmlState) marshalField(f *xmlField, fv reflect.Value, level int) error {
+	for f.mode != xmlElement && (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) {
+		fv = fv.Elem()
+	}
+
+	switch f.mode {
+	case xmlElement:
+		return e.marshalValue(fv, xml.Name{Space: f.xmlns, Local: f.name}, level)
+	case xmlInnerXML:
+		text, err := xmlText(fv)
+		if err != nil {
+			return err
+		}
+		e.WriteString(text)
+	case xmlCharData:
+		text, err := xmlText(fv)
+		if err != nil {
+			return err
+		}
+		xml.EscapeText(e, []byte(text))
+	case xmlCData:
+		text, err := xmlText(fv)
+		if err != nil {
+			return err
+		}
+		// `]]>` 需要拆分至两个 CDATA 中
+		e.WriteString("<![CDATA[")
+		e.WriteString(strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>"))
+		e.WriteString("]]>")
+	case xmlComment:
+		text, err := xmlText(fv)
+		if err != nil {
+			return err
+		}
+		if text == "" {
+			return nil
+		}
+		if strings.Contains(text, "--") {
+			return fmt.Errorf(`xml: comments must not contain "--"`)
+		}
+		e.WriteString("<!--")
+		e.WriteString(text)
+		if strings.HasSuffix(text, "-") {
+			// `--->` 不是合法的注释结尾
+			e.WriteByte(' ')
+		}
+		e.WriteString("-->")
+	}
+	return nil
+}
+
+// 属性值
+func xmlAttrValue(fv reflect.Value, name string) (string, error) {
+	if fv.Kind() == reflect.Interface {
+		fv = fv.Elem()
+	}
+	if fv.Type().Implements(xmlMarshalerAttrType) {
+		attr, err := fv.Interface().(xml.MarshalerAttr).MarshalXMLAttr(xml.Name{Local: name})
+		if err != nil {
+			return "", err
+		}
+		return attr.Value, nil
+	}
+	if fv.Kind() == reflect.Ptr && !fv.Type().Implements(textMarshalerType) {
+		fv = fv.Elem()
+	}
+	return xmlText(fv)
+}
+
+// 输出开始标签（attrs 依次为属性名以及属性值）
+func (e *xmlState) writeStart(name xml.Name, attrs []string) {
+	e.WriteByte('<')
+	e.WriteString(name.Local)
+	if name.Space != "" {
+		e.WriteString(` xmlns="`)
+		xml.EscapeText(e, []byte(name.Space))
+		e.WriteByte('"')
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		e.WriteByte(' ')
+		e.WriteString(attrs[i])
+		e.WriteString(`="`)
+		xml.EscapeText(e, []byte(attrs[i+1]))
+		e.WriteByte('"')
+	}
+	e.WriteByte('>')
+}
+
+func (e *xmlState) writeEnd(name string) {
+	e.WriteString("</")
+	e.WriteString(name)
+	e.WriteByte('>')
+}
@@ -0,0 +1,540 @@
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 按照保密级别输出 YAML。
+//
+// 结构体按照其 sifter 的筛选计划输出为 mapping（键的顺序与结构体域的声明顺序一致），yaml 标签的处理方式：
+//  1. `yaml:"name,omitempty"` 设置键名以及 omitempty 选项（空值的判断与 yaml 一致：所有公开的域均为空值的结构体、
+//  IsZero() 返回 true 的值同样视为空值）；没有 yaml 标签时使用 json 标签中的别名以及 omitempty/omitzero 选项
+//  （空值的判断与 json 一致）；`yaml:"-"` 表示忽略（`json:"-"` 的域同样被忽略）；
+//  2. 重命名之后的同名键按照与 json 相同的规则处理（层次较浅的优先，同一层次中设置了标签的优先，无法区分的全部忽略）；
+//  3. `yaml:",inline"` 将结构体（或者键为字符串的映射）的域展开至当前层，展开的键与已有的键重复时返回错误；
+//  匿名结构体域与 json 一样总是被展开；
+//  4. `yaml:",flow"` 按照 flow 风格输出（`{a: 1, b: 2}`、`[1, 2]`）。
+//
+// 其余类型：time.Time 输出为 RFC3339 时间戳，[]byte 输出为 !!binary（base64），实现了 encoding.TextMarshaler 的类型
+// 输出为字符串，仅实现了 json.Marshaler 的类型将其 json 转换为 YAML 输出；映射的键与 json 一样转换为字符串并排序。
+
+// 输出之前的中间节点
+type yamlNode struct {
+	kind   int
+	scalar string      // yamlScalar: 已经转义/加引号的标量
+	keys   []string    // yamlMapping: 已经转义/加引号的键
+	items  []*yamlNode // yamlMapping: 键对应的值；yamlSequence: 元素
+	flow   bool
+}
+
+const (
+	yamlScalar = iota
+	yamlMapping
+	yamlSequence
+)
+
+var yamlNull = &yamlNode{kind: yamlScalar, scalar: "null"}
+
+type yamlState struct {
+	siftState
+}
+
+// yaml 标签重命名之后因为同名而被忽略的域
+var yamlDroppedCache sync.Map // map[*cachedSifter]map[*sifterItem]bool
+
+// 实现了此接口的值在 omitempty 时按照 IsZero() 判断是否为空值（与 yaml 一致）
+type yamlIsZeroer interface {
+	IsZero() bool
+}
+
+// api function
+//
+// 按照保密级别对任意值进行 YAML 序列化。
+//
+// @param
+//  v - 需要执行筛选/脱敏的值（结构体、指针、切片、映射等均可）
+//  maxConfidentialLevel - 最高允许的安全等级（高于此等级的将被筛除）
+func MarshalYAMLLevel(v interface{}, maxConfidentialLevel int) ([]byte, error) {
+	n := yamlNull
+	if v != nil {
+		var err error
+		if n, err = (&yamlState{}).node(reflect.ValueOf(v), maxConfidentialLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	writeYamlNode(&buf, n, 0)
+	return buf.Bytes(), nil
+}
+
+func (e *yamlState) node(rv reflect.Value, level int) (*yamlNode, error) {
+	t := rv.Type()
+
+	// 指针接收者实现的序列化方法只在值可寻址时生效（与 json 一致）
+	if t.Kind() != reflect.Ptr && rv.CanAddr() && isMarshalerType(reflect.PtrTo(t)) {
+		return e.marshalerNode(rv.Addr())
+	}
+	if isMarshalerType(t) {
+		return e.marshalerNode(rv)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return yamlScalarNode(strconv.FormatBool(rv.Bool())), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return yamlScalarNode(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return yamlScalarNode(strconv.FormatUint(rv.Uint(), 10)), nil
+	case reflect.Float32:
+		return yamlScalarNode(formatYamlFloat(rv.Float(), 32)), nil
+	case reflect.Float64:
+		return yamlScalarNode(formatYamlFloat(rv.Float(), 64)), nil
+	case reflect.String:
+		if t == numberType {
+			if rv.String() == "" {
+				return yamlScalarNode("0"), nil
+			}
+			return yamlScalarNode(rv.String()), nil
+		}
+		return yamlScalarNode(quoteYamlString(rv.String())), nil
+	case reflect.Interface:
+		if rv.IsNil() {
+			return yamlNull, nil
+		}
+		return e.node(rv.Elem(), level)
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return yamlNull, nil
+		}
+		if err := e.enter(rv); err != nil {
+			return nil, err
+		}
+		defer e.leave(rv)
+		return e.node(rv.Elem(), level)
+	case reflect.Struct:
+		return e.structNode(rv, level)
+	case reflect.Map:
+		return e.mapNode(rv, level)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return yamlNull, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 && !isMarshalerType(reflect.PtrTo(t.Elem())) {
+			return yamlScalarNode("!!binary " + base64.StdEncoding.EncodeToString(rv.Bytes())), nil
+		}
+		if err := e.enter(rv); err != nil {
+			return nil, err
+		}
+		defer e.leave(rv)
+		return e.sequenceNode(rv, level)
+	case reflect.Array:
+		return e.sequenceNode(rv, level)
+	default:
+		return nil, fmt.Errorf("yaml: unsupported type: %v", t)
+	}
+}
+
+func (e *yamlState) marshalerNode(rv reflect.Value) (*yamlNode, error) {
+	if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return yamlNull, nil
+	}
+
+	switch m := rv.Interface().(type) {
+	case time.Time:
+		return yamlScalarNode(m.Format(time.RFC3339Nano)), nil
+	case *time.Time:
+		return yamlScalarNode(m.Format(time.RFC3339Nano)), nil
+	case json.Marshaler:
+		b, err := m.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("json: error calling MarshalJSON for type %v: %v", rv.Type(), err)
+		}
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return nil, fmt.Errorf("json: error calling MarshalJSON for type %v: %v", rv.Type(), err)
+		}
+		if v == nil {
+			return yamlNull, nil
+		}
+		return e.node(reflect.ValueOf(v), CONFIDENTIAL_LEVEL_MAX)
+	case encoding.TextMarshaler:
+		b, err := m.MarshalText()
+		if err != nil {
+			return nil, fmt.Errorf("json: error calling MarshalText for type %v: %v", rv.Type(), err)
+		}
+		return yamlScalarNode(quoteYamlString(string(b))), nil
+	}
+	return yamlNull, nil
+}
+
+func (e *yamlState) structNode(rv reflect.Value, level int) (*yamlNode, error) {
+	cs, err := getSifter(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	dropped := yamlDroppedItems(cs)
+	seen := make(map[string]bool)
+
+	n := &yamlNode{kind: yamlMapping}
+	for _, si := range cs.visibleItems(level) {
+		yt := si.yaml
+		if (yt != nil && yt.ignore) || dropped[si] {
+			continue
+		}
+
+		fv, ok := fieldByIndex(rv, si.index)
+		if !ok || !fv.IsValid() || !fv.CanInterface() {
+			continue
+		}
+		if yt != nil {
+			if yt.omitEmpty && isYamlEmptyValue(fv) {
+				continue
+			}
+		} else if (si.isOmitEmpty && isEmptyValue(fv)) || (si.isZero != nil && si.isZero(fv)) {
+			continue
+		}
+
+		child, err := e.node(fv, level)
+		if err != nil {
+			return nil, err
+		}
+
+		if yt != nil && yt.inline {
+			if child == yamlNull {
+				continue
+			}
+			if child.kind != yamlMapping {
+				return nil, fmt.Errorf("yaml: option ,inline needs a struct value or map field: %s", si.field)
+			}
+			for _, key := range child.keys {
+				if seen[key] {
+					return nil, fmt.Errorf("yaml: duplicated key %s in inlined field %s of %v", key, si.field, rv.Type())
+				}
+				seen[key] = true
+			}
+			n.keys = append(n.keys, child.keys...)
+			n.items = append(n.items, child.items...)
+			continue
+		}
+
+		if yt != nil && yt.flow {
+			child = flowYamlNode(child)
+		}
+		name := si.alias
+		if yt != nil && yt.alias != "" {
+			name = yt.alias
+		}
+		key := quoteYamlString(name)
+		if seen[key] {
+			return nil, fmt.Errorf("yaml: duplicated key %s in inlined fields of %v", key, rv.Type())
+		}
+		seen[key] = true
+		n.keys = append(n.keys, key)
+		n.items = append(n.items, child)
+	}
+	return n, nil
+}
+
+// 处理 yaml 标签重命名之后的同名键（展开的域没有自身的键，展开之后的重复键在输出时检查）
+func yamlDroppedItems(cs *cachedSifter) map[*sifterItem]bool {
+	if dropped, ok := yamlDroppedCache.Load(cs); ok {
+		return dropped.(map[*sifterItem]bool)
+	}
+
+	indexes := make([][]int, len(cs.sifterItems))
+	names := make([]string, len(cs.sifterItems))
+	tagged := make([]bool, len(cs.sifterItems))
+	for i, si := range cs.sifterItems {
+		indexes[i] = si.index
+		yt := si.yaml
+		if yt != nil && (yt.ignore || yt.inline) {
+			continue
+		}
+		names[i], tagged[i] = si.alias, si.isTagged
+		if yt != nil && yt.alias != "" {
+			names[i], tagged[i] = yt.alias, true
+		}
+	}
+
+	dropped := make(map[*sifterItem]bool)
+	for i, keep := range dominantKeys(indexes, names, tagged) {
+		if !keep && names[i] != "" {
+			dropped[cs.sifterItems[i]] = true
+		}
+	}
+	yamlDroppedCache.Store(cs, dropped)
+	return dropped
+}
+
+// 判断 omitempty 时是否为空值（与 yaml 一致，不同于 json：结构体的所有公开域均为空值时同样视为空值）
+// @refer `gopkg.in/yaml.v3/yaml.go` isZero()
+func isYamlEmptyValue(v reflect.Value) bool {
+	if z, ok := v.Interface().(yamlIsZeroer); ok {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return true
+		}
+		return z.IsZero()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Struct:
+		vt := v.Type()
+		for i := v.NumField() - 1; i >= 0; i-- {
+			if vt.Field(i).PkgPath != "" {
+				// 非公开的域
+				continue
+			}
+			if !isYamlEmptyValue(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (e *yamlState) mapNode(rv reflect.Value, level int) (*yamlNode, error) {
+	if rv.IsNil() {
+		return yamlNull, nil
+	}
+	if !isValidMapKeyType(rv.Type().Key()) {
+		return nil, fmt.Errorf("yaml: unsupported type: %v", rv.Type())
+	}
+	if err := e.enter(rv); err != nil {
+		return nil, err
+	}
+	defer e.leave(rv)
+
+	type keyValue struct {
+		key   string
+		value reflect.Value
+	}
+	kvs := make([]keyValue, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k, err := resolveKeyName(iter.Key())
+		if err != nil {
+			return nil, fmt.Errorf("yaml: encoding error for type %v: %v", rv.Type(), err)
+		}
+		kvs = append(kvs, keyValue{key: k, value: iter.Value()})
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].key < kvs[j].key
+	})
+
+	n := &yamlNode{kind: yamlMapping}
+	for _, kv := range kvs {
+		child, err := e.node(kv.value, level)
+		if err != nil {
+			return nil, err
+		}
+		n.keys = append(n.keys, quoteYamlString(kv.key))
+		n.items = append(n.items, child)
+	}
+	return n, nil
+}
+
+func (e *yamlState) sequenceNode(rv reflect.Value, level int) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlSequence, items: make([]*yamlNode, rv.Len())}
+	for i := range n.items {
+		child, err := e.node(rv.Index(i), level)
+		if err != nil {
+			return nil, err
+		}
+		n.items[i] = child
+	}
+	return n, nil
+}
+
+func yamlScalarNode(s string) *yamlNode {
+	return &yamlNode{kind: yamlScalar, scalar: s}
+}
+
+// 按照 flow 风格输出的节点（嵌套的节点同样按照 flow 风格输出）
+func flowYamlNode(n *yamlNode) *yamlNode {
+	if n.kind == yamlScalar {
+		return n
+	}
+	flow := *n
+	flow.flow = true
+	return &flow
+}
+
+// 节点是否可以直接写在键（或者 `- `）之后
+func (n *yamlNode) isInline() bool {
+	return n.kind == yamlScalar || n.flow || len(n.items) == 0
+}
+
+// 以 flow 风格（单行）输出节点
+func writeYamlFlow(buf *bytes.Buffer, n *yamlNode) {
+	switch n.kind {
+	case yamlScalar:
+		buf.WriteString(n.scalar)
+	case yamlMapping:
+		buf.WriteByte('{')
+		for i, child := range n.items {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(n.keys[i])
+			buf.WriteString(": ")
+			writeYamlFlow(buf, child)
+		}
+		buf.WriteByte('}')
+	case yamlSequence:
+		buf.WriteByte('[')
+		for i, child := range n.items {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeYamlFlow(buf, child)
+		}
+		buf.WriteByte(']')
+	}
+}
+
+// 以 block 风格输出节点，每一行缩进 indent 个空格
+func writeYamlNode(buf *bytes.Buffer, n *yamlNode, indent int) {
+	if n.isInline() {
+		buf.WriteString(strings.Repeat(" ", indent))
+		writeYamlFlow(buf, n)
+		buf.WriteByte('\n')
+		return
+	}
+
+	pad := strings.Repeat(" ", indent)
+	for i, child := range n.items {
+		if n.kind == yamlMapping {
+			buf.WriteString(pad)
+			buf.WriteString(n.keys[i])
+			buf.WriteByte(':')
+			if child.isInline() {
+				buf.WriteByte(' ')
+				writeYamlFlow(buf, child)
+				buf.WriteByte('\n')
+			} else {
+				buf.WriteByte('\n')
+				writeYamlNode(buf, child, indent+2)
+			}
+			continue
+		}
+
+		// 序列的元素：第一行写在 `- ` 之后，其余的行缩进至与之对齐
+		buf.WriteString(pad)
+		buf.WriteString("- ")
+		if child.isInline() {
+			writeYamlFlow(buf, child)
+			buf.WriteByte('\n')
+		} else {
+			var sub bytes.Buffer
+			writeYamlNode(&sub, child, indent+2)
+			buf.Write(sub.Bytes()[indent+2:])
+		}
+	}
+}
+
+func formatYamlFloat(f float64, bits int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".eEn") {
+		// 保持浮点数的类型（如 1 输出为 1.0）
+		s += ".0"
+	}
+	return s
+}
+
+// 按照 YAML 的规则输出字符串：可以作为 plain scalar 的原样输出，否则使用双引号并转义
+func quoteYamlString(s string) string {
+	if isPlainYamlString(s) {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f || r == 0x85 || r == '\u2028' || r == '\u2029' || r == '\ufeff':
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// 会被解析为 null/bool（以及 merge key 等）的 plain scalar（YAML 1.1 以及 1.2）
+var yamlReservedWords = map[string]bool{
+	"~": true, "null": true, "true": true, "false": true, "yes": true, "no": true,
+	"on": true, "off": true, "y": true, "n": true, "<<": true, "=": true,
+}
+
+func isPlainYamlString(s string) bool {
+	if s == "" || !utf8.ValidString(s) || yamlReservedWords[strings.ToLower(s)] {
+		return false
+	}
+	if s[0] == ' ' || s[len(s)-1] == ' ' || s[len(s)-1] == ':' {
+		return false
+	}
+	// 以指示符开头
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`.+0123456789", rune(s[0])) {
+		// 以数字、`.`、`+`、`-` 开头的字符串可能被解析为数字
+		return false
+	}
+	// flow 风格中的指示符以及注释、键值的分隔符
+	if strings.ContainsAny(s, ",[]{}") || strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f || r == 0x85 || r == '\u2028' || r == '\u2029' || r == '\ufeff' {
+			return false
+		}
+	}
+	return true
+}